@@ -13,6 +13,7 @@ import (
 	v1 "github.com/zero-shubham/surveysvc/api/v1"
 	"github.com/zero-shubham/surveysvc/config"
 	db "github.com/zero-shubham/surveysvc/db/orm"
+	"github.com/zero-shubham/surveysvc/internal"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
@@ -22,17 +23,40 @@ import (
 type Router struct {
 	server *gin.Engine
 	logger *zerolog.Logger
-	conn   db.DBTX
+	conn   db.Conn
+	opts   []internal.ServiceOption
+	// waitFor are closed by whatever has to be stopped before the server
+	// shuts down.
+	waitFor []<-chan struct{}
+	done    chan struct{}
 }
 
-func NewRouter(logger *zerolog.Logger, conn db.DBTX) *Router {
+// NewRouter returns a router whose handlers use a service built with opts.
+func NewRouter(logger *zerolog.Logger, conn db.Conn, opts ...internal.ServiceOption) *Router {
 	return &Router{
 		server: gin.Default(),
 		logger: logger,
 		conn:   conn,
+		opts:   opts,
+		done:   make(chan struct{}),
 	}
 }
 
+// ShutdownAfter makes the server wait, once the context passed to Start is
+// done, for done to be closed before shutting down. Responses that do not end
+// on their own, such as live streams, have to be ended that way, or the
+// shutdown times out waiting for them.
+func (r *Router) ShutdownAfter(done <-chan struct{}) *Router {
+	r.waitFor = append(r.waitFor, done)
+	return r
+}
+
+// Done is closed once the server has shut down after the context passed to
+// Start is done.
+func (r *Router) Done() <-chan struct{} {
+	return r.done
+}
+
 func (r *Router) ErrorHandler(c *gin.Context) {
 	c.Next()
 
@@ -75,9 +99,11 @@ func (r *Router) Start(ctx context.Context, tp trace.TracerProvider, mp metric.M
 		},
 	)
 
-	v1.NewApiV1Service(r.server, r.conn, r.logger)
+	v1.NewApiV1Service(r.server, r.conn, r.logger, r.opts...)
 
 	go func() {
+		defer close(r.done)
+
 		// Create context that listens for the interrupt signal from the OS.
 		ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
 		defer stop()
@@ -106,6 +132,14 @@ func (r *Router) Start(ctx context.Context, tp trace.TracerProvider, mp metric.M
 		// the request it is currently handling
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
+
+		for _, done := range r.waitFor {
+			select {
+			case <-done:
+			case <-ctx.Done():
+			}
+		}
+
 		if err := srv.Shutdown(ctx); err != nil {
 			r.logger.Fatal().Err(err).Msg("server forced to shutdown")
 		}
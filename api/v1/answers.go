@@ -2,46 +2,100 @@ package v1
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"path"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgtype"
 	db "github.com/zero-shubham/surveysvc/db/orm"
+	"github.com/zero-shubham/surveysvc/internal"
+)
+
+const (
+	// IdempotencyKeyHeader lets clients retry an answer submission safely.
+	// A retry with the same key returns the answer stored by the first
+	// request instead of storing it again.
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	maxIdempotencyKeyLength = 255
 )
 
 type CreateAnswerBody struct {
-	SelectedOption string    `json:"selected_option"`
-	AnswerText     string    `json:"answer_text"`
-	UserID         uuid.UUID `json:"user_id" binding:"required"`
-	QuestionID     uuid.UUID `json:"question_id" binding:"required"`
-	QuestionSetID  uuid.UUID `json:"question_set_id" binding:"required"`
+	SelectedOption  string        `json:"selected_option"`
+	SelectedOptions []string      `json:"selected_options"`
+	AnswerText      string        `json:"answer_text"`
+	UserID          uuid.UUID     `json:"user_id" binding:"required"`
+	QuestionID      uuid.UUID     `json:"question_id" binding:"required"`
+	QuestionSetID   uuid.UUID     `json:"question_set_id" binding:"required"`
+	CampaignID      uuid.NullUUID `json:"campaign_id"`
+	ResponseID      uuid.NullUUID `json:"response_id"`
 }
 
 func (svc *ApiV1Service) CreateAnswer(c *gin.Context) {
 	var in CreateAnswerBody
 	if err := c.ShouldBindJSON(&in); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingFieldErrors(err)})
 		return
 	}
 
-	orm := db.New(svc.conn)
-	answer, err := orm.CreateAnswer(c.Request.Context(), db.CreateAnswerParams{
-		SelectedOption: pgtype.Text{String: in.SelectedOption, Valid: in.SelectedOption != ""},
-		AnswerText:     pgtype.Text{String: in.AnswerText, Valid: in.AnswerText != ""},
-		UserID:         in.UserID,
-		QuestionID:     in.QuestionID,
-		QuestionSetID:  in.QuestionSetID,
+	idempotencyKey := c.GetHeader(IdempotencyKeyHeader)
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength)})
+		return
+	}
+
+	answer, created, err := svc.service.CreateAnswer(c.Request.Context(), internal.AnswerInput{
+		SelectedOption:  in.SelectedOption,
+		SelectedOptions: in.SelectedOptions,
+		AnswerText:      in.AnswerText,
+		UserID:          in.UserID,
+		QuestionID:      in.QuestionID,
+		QuestionSetID:   in.QuestionSetID,
+		CampaignID:      in.CampaignID,
+		ResponseID:      in.ResponseID,
+		IdempotencyKey:  internal.ClientIdempotencyKey(idempotencyKey),
 	})
+	if abortWithServiceError(c, err) {
+		return
+	}
 	if err != nil {
-		svc.logger.Err(err).Msg("failed to create answer")
+		svc.logger.Err(err).Ctx(c).Msg("failed to create answer")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create answer"})
 		return
 	}
 
+	if !created {
+		c.JSON(http.StatusOK, answer)
+		return
+	}
+
+	c.Header("Location", path.Join(c.Request.URL.Path, answer.ID.String()))
 	c.JSON(http.StatusCreated, answer)
 }
 
+func (svc *ApiV1Service) GetAnswer(c *gin.Context) {
+	answerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid answer ID"})
+		return
+	}
+
+	answer, err := internal.ResolveAnswer(c.Request.Context(), db.New(svc.conn), answerID)
+	if errors.Is(err, internal.ErrAnswerNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "answer not found"})
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch answer")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch answer"})
+		return
+	}
+
+	c.JSON(http.StatusOK, answer)
+}
+
 type GetAnswersQuery struct {
 	QuestionID string `form:"question_id"`
 	Limit      int    `form:"limit"`
@@ -54,8 +108,8 @@ type GetAnswersResp struct {
 
 func (svc *ApiV1Service) GetAnswers(c *gin.Context) {
 	var query GetAnswersQuery
-	if err := c.BindQuery(&query); err != nil {
-		c.JSON(400, gin.H{"error": "invalid query parameters"})
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters", "fields": bindingFieldErrors(err)})
 		return
 	}
 
@@ -73,7 +127,8 @@ func (svc *ApiV1Service) GetAnswers(c *gin.Context) {
 			Limit:  int32(query.Limit),
 		})
 		if err != nil {
-			c.JSON(500, gin.H{"error": "failed to fetch answers"})
+			svc.logger.Err(err).Ctx(c).Msg("failed to fetch answers")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch answers"})
 			return
 		}
 
@@ -81,8 +136,7 @@ func (svc *ApiV1Service) GetAnswers(c *gin.Context) {
 	} else {
 		questionID, err := uuid.Parse(query.QuestionID)
 		if err != nil {
-			svc.logger.Err(err).Ctx(c).Msg("invalid question id")
-			c.AbortWithError(http.StatusBadRequest, errors.New("invalid query parameters"))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters", "fields": []internal.FieldError{{Field: "question_id", Message: "must be a UUID"}}})
 			return
 		}
 
@@ -92,12 +146,205 @@ func (svc *ApiV1Service) GetAnswers(c *gin.Context) {
 			Offset:     int32(query.Offset),
 		})
 		if err != nil {
-			c.JSON(500, gin.H{"error": "failed to fetch answers"})
+			svc.logger.Err(err).Ctx(c).Msg("failed to fetch answers")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch answers"})
 			return
 		}
 
 		answersResp.Answers = answers
 	}
 
-	c.JSON(200, answersResp)
+	if answersResp.Answers == nil {
+		answersResp.Answers = []db.Answer{}
+	}
+
+	c.JSON(http.StatusOK, answersResp)
+}
+
+type AnswerChangeBody struct {
+	SelectedOption  *string   `json:"selected_option"`
+	SelectedOptions *[]string `json:"selected_options"`
+	AnswerText      *string   `json:"answer_text"`
+}
+
+// ReplaceAnswer overwrites the content of an answer. Fields left out of the
+// body are cleared.
+func (svc *ApiV1Service) ReplaceAnswer(c *gin.Context) {
+	svc.updateAnswer(c, func(in *AnswerChangeBody) {
+		if in.SelectedOption == nil {
+			in.SelectedOption = new(string)
+		}
+		if in.SelectedOptions == nil {
+			in.SelectedOptions = &[]string{}
+		}
+		if in.AnswerText == nil {
+			in.AnswerText = new(string)
+		}
+	})
+}
+
+// PatchAnswer changes the fields of an answer present in the body and keeps
+// the rest.
+func (svc *ApiV1Service) PatchAnswer(c *gin.Context) {
+	svc.updateAnswer(c, func(*AnswerChangeBody) {})
+}
+
+func (svc *ApiV1Service) updateAnswer(c *gin.Context, complete func(in *AnswerChangeBody)) {
+	answerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid answer ID"})
+		return
+	}
+
+	var in AnswerChangeBody
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingFieldErrors(err)})
+		return
+	}
+	complete(&in)
+
+	answer, err := svc.service.UpdateAnswer(c.Request.Context(), answerID, internal.AnswerChange(in))
+	if errors.Is(err, internal.ErrAnswerNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "answer not found"})
+		return
+	}
+	if abortWithServiceError(c, err) {
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to update answer")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update answer"})
+		return
+	}
+
+	c.JSON(http.StatusOK, answer)
+}
+
+type BatchAnswerBody struct {
+	CreateAnswerBody
+	IdempotencyKey string `json:"idempotency_key" binding:"max=255"`
+}
+
+type BatchCreateAnswersBody struct {
+	// Atomic stores either every answer or none of them.
+	Atomic  bool              `json:"atomic"`
+	Answers []BatchAnswerBody `json:"answers" binding:"required,min=1"`
+}
+
+type BatchAnswerResult struct {
+	Index  int                   `json:"index"`
+	Status int                   `json:"status"`
+	Answer *db.Answer            `json:"answer,omitempty"`
+	Error  string                `json:"error,omitempty"`
+	Fields []internal.FieldError `json:"fields,omitempty"`
+}
+
+type BatchCreateAnswersResp struct {
+	Results []BatchAnswerResult `json:"results"`
+}
+
+// BatchCreateAnswers stores up to internal.MaxBatchAnswers answers at once and
+// reports a status per answer, 201 for stored answers, 200 for answers that
+// were stored before under the same idempotency key, and the status a single
+// POST /v1/answers would have failed with otherwise. In atomic mode a single
+// rejected answer keeps every answer from being stored and the request fails
+// with 422. A failure that is not a rejection of an answer, such as a database
+// error, fails the whole request with 500 and stores none of the answers.
+func (svc *ApiV1Service) BatchCreateAnswers(c *gin.Context) {
+	var in BatchCreateAnswersBody
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingFieldErrors(err)})
+		return
+	}
+
+	if len(in.Answers) > internal.MaxBatchAnswers {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d answers can be sent at once", internal.MaxBatchAnswers)})
+		return
+	}
+
+	results := make([]BatchAnswerResult, len(in.Answers))
+	inputs := make([]internal.AnswerInput, 0, len(in.Answers))
+	indexes := make([]int, 0, len(in.Answers))
+	for i, answer := range in.Answers {
+		results[i].Index = i
+
+		// Items are bound without dive so that one bad item does not fail
+		// the whole request.
+		if err := binding.Validator.ValidateStruct(answer); err != nil {
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = "invalid answer"
+			results[i].Fields = bindingFieldErrors(err)
+			continue
+		}
+
+		inputs = append(inputs, internal.AnswerInput{
+			SelectedOption:  answer.SelectedOption,
+			SelectedOptions: answer.SelectedOptions,
+			AnswerText:      answer.AnswerText,
+			UserID:          answer.UserID,
+			QuestionID:      answer.QuestionID,
+			QuestionSetID:   answer.QuestionSetID,
+			CampaignID:      answer.CampaignID,
+			ResponseID:      answer.ResponseID,
+			IdempotencyKey:  internal.ClientIdempotencyKey(answer.IdempotencyKey),
+		})
+		indexes = append(indexes, i)
+	}
+
+	var answerResults []internal.AnswerResult
+	if in.Atomic && len(indexes) < len(in.Answers) {
+		answerResults = make([]internal.AnswerResult, len(indexes))
+		for j := range answerResults {
+			answerResults[j].Err = internal.ErrBatchAborted
+		}
+	} else {
+		var err error
+		answerResults, err = svc.service.CreateAnswers(c.Request.Context(), inputs, in.Atomic)
+		if err != nil {
+			svc.logger.Err(err).Ctx(c).Msg("failed to create answers")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create answers"})
+			return
+		}
+	}
+
+	failed := len(indexes) < len(in.Answers)
+	for j, result := range answerResults {
+		i := indexes[j]
+
+		var verr *internal.ValidationError
+		switch {
+		case errors.As(result.Err, &verr):
+			results[i].Status = http.StatusUnprocessableEntity
+			results[i].Error = "validation failed"
+			results[i].Fields = verr.Fields
+		case result.Err != nil:
+			status, ok := serviceErrorStatus(result.Err)
+			if !ok {
+				// Not a rejection, so not the client's to see.
+				svc.logger.Err(result.Err).Ctx(c).Int("index", i).Msg("failed to create answer")
+				results[i].Status = http.StatusInternalServerError
+				results[i].Error = "failed to create answer"
+				break
+			}
+			results[i].Status = status
+			results[i].Error = result.Err.Error()
+		case result.Created:
+			results[i].Status = http.StatusCreated
+			results[i].Answer = &result.Answer
+		default:
+			results[i].Status = http.StatusOK
+			results[i].Answer = &result.Answer
+		}
+
+		if result.Err != nil {
+			failed = true
+		}
+	}
+
+	if in.Atomic && failed {
+		c.JSON(http.StatusUnprocessableEntity, BatchCreateAnswersResp{Results: results})
+		return
+	}
+
+	c.JSON(http.StatusOK, BatchCreateAnswersResp{Results: results})
 }
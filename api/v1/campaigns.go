@@ -0,0 +1,286 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+	"github.com/zero-shubham/surveysvc/internal"
+)
+
+func timestamptz(t *time.Time) pgtype.Timestamptz {
+	if t == nil {
+		return pgtype.Timestamptz{}
+	}
+
+	return pgtype.Timestamptz{Time: *t, Valid: true}
+}
+
+type CampaignSettings struct {
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+
+	// AnswerPolicy overrides the answer policy of the question sets the
+	// campaign runs. Left null, each question set's own policy applies.
+	AnswerPolicy db.NullAnswerPolicy `json:"answer_policy"`
+}
+
+func (s CampaignSettings) validate() error {
+	if s.StartsAt != nil && s.EndsAt != nil && !s.EndsAt.After(*s.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+
+	if s.AnswerPolicy.Valid && !s.AnswerPolicy.AnswerPolicy.Valid() {
+		return errInvalidAnswerPolicy
+	}
+
+	return nil
+}
+
+type CreateCampaignBody struct {
+	OrgID uuid.UUID `json:"org_id" binding:"required"`
+	Name  string    `json:"name" binding:"required"`
+	CampaignSettings
+}
+
+func (svc *ApiV1Service) CreateCampaign(c *gin.Context) {
+	var in CreateCampaignBody
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingFieldErrors(err)})
+		return
+	}
+
+	if err := in.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	orm := db.New(svc.conn)
+	campaign, err := orm.CreateCampaign(c.Request.Context(), db.CreateCampaignParams{
+		OrgID:        in.OrgID,
+		Name:         in.Name,
+		Status:       db.CampaignStatusDraft,
+		StartsAt:     timestamptz(in.StartsAt),
+		EndsAt:       timestamptz(in.EndsAt),
+		AnswerPolicy: in.AnswerPolicy,
+	})
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to create campaign")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create campaign"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, campaign)
+}
+
+type GetCampaignsQuery struct {
+	OrgID  string `form:"org_id" binding:"required"`
+	Limit  int    `form:"limit"`
+	Offset int    `form:"offset"`
+}
+
+type GetCampaignsResp struct {
+	Campaigns []db.Campaign `json:"campaigns"`
+}
+
+func (svc *ApiV1Service) GetCampaigns(c *gin.Context) {
+	var query GetCampaignsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters"})
+		return
+	}
+
+	orgID, err := uuid.Parse(query.OrgID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters"})
+		return
+	}
+
+	if query.Limit == 0 {
+		query.Limit = 10
+	}
+
+	orm := db.New(svc.conn)
+	campaigns, err := orm.GetCampaignsByOrgID(c.Request.Context(), db.GetCampaignsByOrgIDParams{
+		OrgID:  orgID,
+		Limit:  int32(query.Limit),
+		Offset: int32(query.Offset),
+	})
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch campaigns")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch campaigns"})
+		return
+	}
+
+	c.JSON(http.StatusOK, GetCampaignsResp{Campaigns: campaigns})
+}
+
+func (svc *ApiV1Service) GetCampaign(c *gin.Context) {
+	campaignID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid campaign ID"})
+		return
+	}
+
+	orm := db.New(svc.conn)
+	campaign, err := internal.ResolveCampaign(c.Request.Context(), orm, campaignID)
+	if errors.Is(err, internal.ErrCampaignNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "campaign not found"})
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch campaign")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch campaign"})
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+type UpdateCampaignBody struct {
+	Name string `json:"name" binding:"required"`
+	CampaignSettings
+}
+
+func (svc *ApiV1Service) UpdateCampaign(c *gin.Context) {
+	campaignID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid campaign ID"})
+		return
+	}
+
+	var in UpdateCampaignBody
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingFieldErrors(err)})
+		return
+	}
+
+	if err := in.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := svc.conn.Begin(ctx)
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to begin transaction")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update campaign"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	orm := db.New(svc.conn).WithTx(tx)
+	campaign, err := orm.GetCampaignByIDForUpdate(ctx, campaignID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "campaign not found"})
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch campaign")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update campaign"})
+		return
+	}
+
+	if campaign.Status == db.CampaignStatusClosed {
+		c.JSON(http.StatusConflict, gin.H{"error": "closed campaigns cannot be changed"})
+		return
+	}
+
+	campaign, err = orm.UpdateCampaignByID(ctx, db.UpdateCampaignByIDParams{
+		ID:           campaignID,
+		Name:         in.Name,
+		StartsAt:     timestamptz(in.StartsAt),
+		EndsAt:       timestamptz(in.EndsAt),
+		AnswerPolicy: in.AnswerPolicy,
+	})
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to update campaign")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update campaign"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to commit campaign")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update campaign"})
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+type UpdateCampaignStatusBody struct {
+	Status db.CampaignStatus `json:"status" binding:"required"`
+}
+
+// UpdateCampaignStatus moves a campaign through its lifecycle. Scheduling a
+// campaign requires a start time, and a campaign whose end has passed counts
+// as closed.
+func (svc *ApiV1Service) UpdateCampaignStatus(c *gin.Context) {
+	campaignID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid campaign ID"})
+		return
+	}
+
+	var in UpdateCampaignStatusBody
+	if err := c.ShouldBindJSON(&in); err != nil || !in.Status.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := svc.conn.Begin(ctx)
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to begin transaction")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update campaign status"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	orm := db.New(svc.conn).WithTx(tx)
+	campaign, err := orm.GetCampaignByIDForUpdate(ctx, campaignID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "campaign not found"})
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch campaign")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update campaign status"})
+		return
+	}
+
+	current := internal.EffectiveCampaignStatus(campaign, time.Now())
+	if !internal.CanTransitionCampaign(current, in.Status) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("campaign cannot move from %s to %s", current, in.Status)})
+		return
+	}
+
+	if in.Status == db.CampaignStatusScheduled && !campaign.StartsAt.Valid {
+		c.JSON(http.StatusConflict, gin.H{"error": "campaign needs starts_at to be scheduled"})
+		return
+	}
+
+	campaign, err = orm.UpdateCampaignStatus(ctx, db.UpdateCampaignStatusParams{
+		ID:     campaignID,
+		Status: in.Status,
+	})
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to update campaign status")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update campaign status"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to commit campaign status")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update campaign status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
@@ -0,0 +1,99 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/zero-shubham/surveysvc/internal"
+)
+
+// useJSONFieldNames makes binding errors name fields the way clients send
+// them rather than by their Go struct field names.
+func useJSONFieldNames() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+}
+
+// bindingFieldErrors lists the fields behind an error returned while binding
+// a request body, or nothing if the error cannot be tied to a field.
+func bindingFieldErrors(err error) []internal.FieldError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]internal.FieldError, 0, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			fields = append(fields, internal.FieldError{
+				Field:   fieldErr.Field(),
+				Message: "failed on the '" + fieldErr.Tag() + "' rule",
+			})
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []internal.FieldError{{
+			Field:   typeErr.Field,
+			Message: "must be of type " + typeErr.Type.String(),
+		}}
+	}
+
+	return nil
+}
+
+// serviceErrorStatus maps the errors returned by internal.Service when it
+// rejects a request to the status they are reported with.
+func serviceErrorStatus(err error) (int, bool) {
+	switch {
+	case errors.Is(err, internal.ErrQuestionNotFound),
+		errors.Is(err, internal.ErrQuestionSetNotFound),
+		errors.Is(err, internal.ErrQuestionNotInSet),
+		errors.Is(err, internal.ErrCampaignNotFound),
+		errors.Is(err, internal.ErrResponseNotFound),
+		errors.Is(err, internal.ErrResponseMismatch),
+		errors.Is(err, internal.ErrQuestionSkipped),
+		errors.Is(err, internal.ErrIdempotencyKeyReused):
+		return http.StatusUnprocessableEntity, true
+	case errors.Is(err, internal.ErrQuestionSetNotPublished),
+		errors.Is(err, internal.ErrCampaignNotOpen),
+		errors.Is(err, internal.ErrResponseClosed),
+		errors.Is(err, internal.ErrDuplicateAnswer):
+		return http.StatusConflict, true
+	case errors.Is(err, internal.ErrBatchAborted):
+		return http.StatusFailedDependency, true
+	}
+
+	return 0, false
+}
+
+// abortWithServiceError replies to a request rejected by internal.Service and
+// reports whether err was such a rejection.
+func abortWithServiceError(c *gin.Context, err error) bool {
+	var verr *internal.ValidationError
+	if errors.As(err, &verr) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "validation failed", "fields": verr.Fields})
+		return true
+	}
+
+	if status, ok := serviceErrorStatus(err); ok {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return true
+	}
+
+	return false
+}
@@ -1,32 +1,98 @@
 package v1
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
 	db "github.com/zero-shubham/surveysvc/db/orm"
+	"github.com/zero-shubham/surveysvc/internal"
 )
 
 type ApiV1Service struct {
-	conn   db.DBTX
-	logger *zerolog.Logger
+	conn    db.Conn
+	logger  *zerolog.Logger
+	service *internal.Service
 }
 
 type RouterGroupCreator interface {
 	Group(relativePath string, handlers ...gin.HandlerFunc) *gin.RouterGroup
 }
 
-func NewApiV1Service(rgc RouterGroupCreator, conn db.DBTX, logger *zerolog.Logger) *ApiV1Service {
+func NewApiV1Service(rgc RouterGroupCreator, conn db.Conn, logger *zerolog.Logger, opts ...internal.ServiceOption) *ApiV1Service {
 	v1Api := ApiV1Service{
-		conn:   conn,
-		logger: logger,
+		conn:    conn,
+		logger:  logger,
+		service: internal.NewService(logger, conn, opts...),
 	}
 
+	useJSONFieldNames()
+
 	v1 := rgc.Group("/v1")
 	v1.POST("/question-mappings", v1Api.CreateQuestionMapping)
 	v1.GET("/question-mappings", v1Api.GetQuestionMappings)
 	v1.PATCH("/question-mappings/:id", v1Api.UpdateQuestionMapping)
 
+	v1.POST("/answers", v1Api.CreateAnswer)
 	v1.GET("/answers", v1Api.GetAnswers)
+	v1.GET("/answers/:id", v1Api.GetAnswer)
+	v1.POST("/:resource", v1Api.customMethods(map[string]gin.HandlerFunc{
+		"answers:batch": v1Api.BatchCreateAnswers,
+	}))
+	v1.PUT("/answers/:id", v1Api.ReplaceAnswer)
+	v1.PATCH("/answers/:id", v1Api.PatchAnswer)
+
+	v1.POST("/questions", v1Api.CreateQuestion)
+	v1.GET("/questions", v1Api.GetQuestions)
+	v1.GET("/questions/:id", v1Api.GetQuestion)
+	v1.PATCH("/questions/:id", v1Api.UpdateQuestion)
+	v1.DELETE("/questions/:id", v1Api.DeleteQuestion)
+	v1.GET("/questions/:id/results", v1Api.GetQuestionResults)
+
+	v1.POST("/question-sets", v1Api.CreateQuestionSet)
+	v1.GET("/question-sets", v1Api.GetQuestionSets)
+	v1.GET("/question-sets/:id", v1Api.GetQuestionSet)
+	v1.PATCH("/question-sets/:id", v1Api.UpdateQuestionSet)
+	v1.POST("/question-sets/:id/publish", v1Api.PublishQuestionSet)
+	v1.POST("/question-sets/:id/archive", v1Api.ArchiveQuestionSet)
+	v1.POST("/question-sets/:id/versions", v1Api.CreateQuestionSetVersion)
+	v1.GET("/question-sets/:id/rules", v1Api.GetQuestionSetRules)
+	v1.PUT("/question-sets/:id/rules", v1Api.SetQuestionSetRules)
+
+	v1.POST("/campaigns", v1Api.CreateCampaign)
+	v1.GET("/campaigns", v1Api.GetCampaigns)
+	v1.GET("/campaigns/:id", v1Api.GetCampaign)
+	v1.PATCH("/campaigns/:id", v1Api.UpdateCampaign)
+	v1.POST("/campaigns/:id/status", v1Api.UpdateCampaignStatus)
+	v1.GET("/campaigns/:id/summary", v1Api.GetCampaignSummary)
+	v1.GET("/campaigns/:id/live", v1Api.StreamCampaign)
+
+	v1.POST("/responses", v1Api.StartResponse)
+	v1.GET("/responses/:id", v1Api.GetResponse)
+	v1.POST("/responses/:id/submit", v1Api.SubmitResponse)
+	v1.GET("/responses/:id/next-question", v1Api.GetNextQuestion)
+
+	v1.POST("/webhooks", v1Api.CreateWebhook)
+	v1.GET("/webhooks", v1Api.GetWebhooks)
+	v1.GET("/webhooks/:id", v1Api.GetWebhook)
+	v1.PUT("/webhooks/:id", v1Api.UpdateWebhook)
+	v1.DELETE("/webhooks/:id", v1Api.DeleteWebhook)
+	v1.GET("/webhooks/:id/deliveries", v1Api.GetWebhookDeliveries)
 
 	return &v1Api
 }
+
+// customMethods serves custom methods such as POST /v1/answers:batch. gin
+// cannot route a colon in the middle of a path segment, so they share a
+// wildcard route that static routes take precedence over.
+func (svc *ApiV1Service) customMethods(methods map[string]gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		method, ok := methods[c.Param("resource")]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+
+		method(c)
+	}
+}
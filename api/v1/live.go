@@ -0,0 +1,124 @@
+package v1
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+	"github.com/zero-shubham/surveysvc/internal"
+)
+
+// liveHeartbeatInterval keeps idle streams from being closed by proxies.
+const liveHeartbeatInterval = 15 * time.Second
+
+type StreamCampaignQuery struct {
+	QuestionID string `form:"question_id"`
+}
+
+// StreamCampaign streams the answers written to a campaign, by the API or the
+// consumer, as Server-Sent Events. The stream starts with the tallies of every
+// question, then sends an answer event for each answer written and, at most
+// every second, the refreshed tallies of the questions that were answered.
+// question_id narrows the stream to a single question. The stream ends when
+// the server shuts down.
+func (svc *ApiV1Service) StreamCampaign(c *gin.Context) {
+	campaignID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid campaign ID"})
+		return
+	}
+
+	var query StreamCampaignQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters"})
+		return
+	}
+
+	var questionIDs []uuid.UUID
+	if query.QuestionID != "" {
+		questionID, err := uuid.Parse(query.QuestionID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters"})
+			return
+		}
+		questionIDs = []uuid.UUID{questionID}
+	}
+
+	ctx := c.Request.Context()
+	orm := db.New(svc.conn)
+
+	_, err = internal.ResolveCampaign(ctx, orm, campaignID)
+	if errors.Is(err, internal.ErrCampaignNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "campaign not found"})
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch campaign")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to stream campaign"})
+		return
+	}
+
+	// Subscribing before taking the first tallies means no answer falls
+	// between the two.
+	updates, unsubscribe, err := svc.service.SubscribeAnswers(campaignID)
+	if errors.Is(err, internal.ErrLiveUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	defer unsubscribe()
+
+	tallies, err := svc.service.AnswerTallies(ctx, campaignID, questionIDs)
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to tally answers")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to stream campaign"})
+		return
+	}
+
+	c.SSEvent("tallies", tallies)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(liveHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+
+		case update, ok := <-updates:
+			if !ok {
+				// Dropped for falling behind, or the server is shutting
+				// down; the client reconnects and starts again from fresh
+				// tallies.
+				return false
+			}
+
+			if update.Answer != nil {
+				if len(questionIDs) == 0 || update.Answer.QuestionID == questionIDs[0] {
+					c.SSEvent("answer", update.Answer)
+				}
+				return true
+			}
+
+			tallies := update.Tallies
+			if len(questionIDs) > 0 {
+				tallies = slices.DeleteFunc(slices.Clone(tallies), func(tally internal.QuestionTally) bool {
+					return tally.QuestionID != questionIDs[0]
+				})
+			}
+			if len(tallies) > 0 {
+				c.SSEvent("tallies", tallies)
+			}
+
+		case <-heartbeat.C:
+			c.SSEvent("heartbeat", time.Now())
+		}
+
+		return true
+	})
+}
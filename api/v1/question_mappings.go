@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	db "github.com/zero-shubham/surveysvc/db/orm"
+	"github.com/zero-shubham/surveysvc/internal"
 )
 
 type CreateQuestionMappingBody struct {
@@ -19,10 +20,9 @@ func (svc *ApiV1Service) CreateQuestionMapping(c *gin.Context) {
 	orm := db.New(svc.conn)
 	var in CreateQuestionMappingBody
 
-	err := c.BindJSON(&in)
+	err := c.ShouldBindJSON(&in)
 	if err != nil {
-		svc.logger.Err(err).Msg("failed to parse request body")
-		c.AbortWithError(http.StatusBadRequest, errors.New("bad request body"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingFieldErrors(err)})
 		return
 	}
 
@@ -32,8 +32,9 @@ func (svc *ApiV1Service) CreateQuestionMapping(c *gin.Context) {
 		OrgID:      in.OrgID,
 	})
 	if err != nil {
-		svc.logger.Err(err).Msg("failed to create question_mapping")
-		c.AbortWithError(http.StatusInternalServerError, errors.New("somethign went wrong"))
+		svc.logger.Err(err).Ctx(c).Msg("failed to create question_mapping")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create question mapping"})
+		return
 	}
 
 	c.JSON(http.StatusOK, qm)
@@ -51,16 +52,14 @@ type GetQuestionMappingsResp struct {
 
 func (svc *ApiV1Service) GetQuestionMappings(c *gin.Context) {
 	var query GetQuestionMappingsQuery
-	if err := c.BindQuery(&query); err != nil {
-		svc.logger.Err(err).Ctx(c).Msg("invalid query parameters")
-		c.AbortWithError(http.StatusBadRequest, errors.New("invalid query parameters"))
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters", "fields": bindingFieldErrors(err)})
 		return
 	}
 
 	campaignID, err := uuid.Parse(query.CampaignID)
 	if err != nil {
-		svc.logger.Err(err).Ctx(c).Msg("invalid campaign id")
-		c.AbortWithError(http.StatusBadRequest, errors.New("invalid query parameters"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters", "fields": []internal.FieldError{{Field: "campaign_id", Message: "must be a UUID"}}})
 		return
 	}
 
@@ -76,7 +75,7 @@ func (svc *ApiV1Service) GetQuestionMappings(c *gin.Context) {
 	})
 	if err != nil {
 		svc.logger.Err(err).Ctx(c).Msg("failed to get question mappings")
-		c.AbortWithError(http.StatusInternalServerError, errors.New("something went wrong"))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch question mappings"})
 		return
 	}
 
@@ -85,10 +84,6 @@ func (svc *ApiV1Service) GetQuestionMappings(c *gin.Context) {
 	c.JSON(http.StatusOK, GetQuestionMappingsResp{QuestionMappings: mappings})
 }
 
-type UpdateQuestionMappingURI struct {
-	ID uuid.UUID `uri:"id" binding:"required"`
-}
-
 type UpdateQuestionMappingBody struct {
 	QuestionID uuid.UUID `json:"question_id" binding:"required"`
 	CampaignID uuid.UUID `json:"campaign_id" binding:"required"`
@@ -96,31 +91,31 @@ type UpdateQuestionMappingBody struct {
 }
 
 func (svc *ApiV1Service) UpdateQuestionMapping(c *gin.Context) {
-	orm := db.New(svc.conn)
-
-	var uri UpdateQuestionMappingURI
-	if err := c.ShouldBindUri(&uri); err != nil {
-		svc.logger.Err(err).Ctx(c).Msg("invalid URI parameters")
-		c.AbortWithError(http.StatusBadRequest, errors.New("invalid question mapping ID"))
+	questionMappingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid question mapping ID"})
 		return
 	}
 
 	var in UpdateQuestionMappingBody
-	if err := c.BindJSON(&in); err != nil {
-		svc.logger.Err(err).Ctx(c).Msg("failed to parse request body")
-		c.AbortWithError(http.StatusBadRequest, errors.New("bad request body"))
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingFieldErrors(err)})
 		return
 	}
 
-	qm, err := orm.UpdateQuestionMappingsByID(c.Request.Context(), db.UpdateQuestionMappingsByIDParams{
-		ID:         uri.ID,
+	qm, err := svc.service.UpdateQuestionMapping(c.Request.Context(), db.UpdateQuestionMappingsByIDParams{
+		ID:         questionMappingID,
 		QuestionID: in.QuestionID,
 		CampaignID: in.CampaignID,
 		OrgID:      in.OrgID,
 	})
+	if errors.Is(err, internal.ErrQuestionMappingNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "question mapping not found"})
+		return
+	}
 	if err != nil {
 		svc.logger.Err(err).Ctx(c).Msg("failed to update question_mapping")
-		c.AbortWithError(http.StatusInternalServerError, errors.New("something went wrong"))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update question mapping"})
 		return
 	}
 
@@ -0,0 +1,161 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+	"github.com/zero-shubham/surveysvc/internal"
+)
+
+type RuleBody struct {
+	QuestionID       uuid.UUID       `json:"question_id" binding:"required"`
+	Operator         db.RuleOperator `json:"operator" binding:"required"`
+	Value            string          `json:"value"`
+	TargetQuestionID uuid.NullUUID   `json:"target_question_id"`
+}
+
+type SetQuestionSetRulesBody struct {
+	Rules []RuleBody `json:"rules" binding:"dive"`
+}
+
+type QuestionSetRulesResp struct {
+	Rules []db.QuestionSetRule `json:"rules"`
+}
+
+// setRules replaces the branching rules of a question set, keeping their
+// order as their evaluation order.
+func setRules(ctx context.Context, orm *db.Queries, questionSetID uuid.UUID, rules []internal.RuleInput) ([]db.QuestionSetRule, error) {
+	err := orm.DeleteQuestionSetRules(ctx, questionSetID)
+	if err != nil {
+		return nil, err
+	}
+
+	created := make([]db.QuestionSetRule, 0, len(rules))
+	for position, rule := range rules {
+		r, err := orm.CreateQuestionSetRule(ctx, db.CreateQuestionSetRuleParams{
+			QuestionSetID:    questionSetID,
+			QuestionID:       rule.QuestionID,
+			Operator:         rule.Operator,
+			Value:            rule.Value,
+			TargetQuestionID: rule.TargetQuestionID,
+			Position:         int32(position),
+		})
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, r)
+	}
+
+	return created, nil
+}
+
+func (svc *ApiV1Service) GetQuestionSetRules(c *gin.Context) {
+	questionSetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid question set ID"})
+		return
+	}
+
+	orm := db.New(svc.conn)
+	_, err = internal.ResolveQuestionSet(c.Request.Context(), orm, questionSetID)
+	if errors.Is(err, internal.ErrQuestionSetNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "question set not found"})
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch question set")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch question set rules"})
+		return
+	}
+
+	rules, err := orm.GetQuestionSetRules(c.Request.Context(), questionSetID)
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch question set rules")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch question set rules"})
+		return
+	}
+
+	if rules == nil {
+		rules = []db.QuestionSetRule{}
+	}
+
+	c.JSON(http.StatusOK, QuestionSetRulesResp{Rules: rules})
+}
+
+// SetQuestionSetRules replaces the branching rules of a draft question set.
+func (svc *ApiV1Service) SetQuestionSetRules(c *gin.Context) {
+	questionSetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid question set ID"})
+		return
+	}
+
+	var in SetQuestionSetRulesBody
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingFieldErrors(err)})
+		return
+	}
+
+	rules := make([]internal.RuleInput, 0, len(in.Rules))
+	for _, rule := range in.Rules {
+		rules = append(rules, internal.RuleInput(rule))
+	}
+
+	ctx := c.Request.Context()
+	tx, err := svc.conn.Begin(ctx)
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to begin transaction")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update question set rules"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	orm := db.New(svc.conn).WithTx(tx)
+	questionSet, err := orm.GetQuestionSetByIDForUpdate(ctx, questionSetID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "question set not found"})
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch question set")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update question set rules"})
+		return
+	}
+
+	if questionSet.Status != db.QuestionSetStatusDraft {
+		c.JSON(http.StatusConflict, gin.H{"error": errQuestionSetNotDraft.Error()})
+		return
+	}
+
+	questions, err := orm.GetQuestionSetQuestions(ctx, questionSetID)
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch question set questions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update question set rules"})
+		return
+	}
+
+	err = internal.ValidateRules(questions, rules)
+	if abortWithServiceError(c, err) {
+		return
+	}
+
+	created, err := setRules(ctx, orm, questionSetID, rules)
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to set question set rules")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update question set rules"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to commit question set rules")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update question set rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, QuestionSetRulesResp{Rules: created})
+}
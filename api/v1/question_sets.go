@@ -0,0 +1,434 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+	"github.com/zero-shubham/surveysvc/internal"
+)
+
+var (
+	errQuestionSetNotDraft   = errors.New("only draft question sets can be changed")
+	errQuestionSetEmpty      = errors.New("question set has no questions")
+	errDuplicateQuestion     = errors.New("question_ids must be unique")
+	errUnknownQuestionInSet  = errors.New("question_ids refers to an unknown question")
+	errQuestionSetTransition = errors.New("question set cannot move to the requested status")
+	errInvalidAnswerPolicy   = errors.New("answer_policy must be one of allow_multiple, keep_latest or reject_duplicates")
+)
+
+type QuestionSetBody struct {
+	Title        string          `json:"title" binding:"required"`
+	Description  string          `json:"description"`
+	QuestionIDs  []uuid.UUID     `json:"question_ids"`
+	AnswerPolicy db.AnswerPolicy `json:"answer_policy"`
+}
+
+// answerPolicy is the policy requested for the question set, allowing
+// multiple answers unless told otherwise.
+func (b QuestionSetBody) answerPolicy() (db.AnswerPolicy, error) {
+	if b.AnswerPolicy == "" {
+		return db.AnswerPolicyAllowMultiple, nil
+	}
+
+	if !b.AnswerPolicy.Valid() {
+		return "", errInvalidAnswerPolicy
+	}
+
+	return b.AnswerPolicy, nil
+}
+
+type QuestionSetResp struct {
+	db.QuestionSet
+	Questions []db.Question `json:"questions"`
+}
+
+// setQuestions replaces the membership of a question set, keeping the order
+// of questionIDs.
+func setQuestions(ctx context.Context, orm *db.Queries, questionSetID uuid.UUID, questionIDs []uuid.UUID) error {
+	seen := make(map[uuid.UUID]struct{}, len(questionIDs))
+	for _, questionID := range questionIDs {
+		if _, ok := seen[questionID]; ok {
+			return errDuplicateQuestion
+		}
+		seen[questionID] = struct{}{}
+	}
+
+	err := orm.DeleteQuestionSetQuestions(ctx, questionSetID)
+	if err != nil {
+		return err
+	}
+
+	for position, questionID := range questionIDs {
+		err := orm.AddQuestionSetQuestion(ctx, db.AddQuestionSetQuestionParams{
+			QuestionSetID: questionSetID,
+			QuestionID:    questionID,
+			Position:      int32(position),
+		})
+		if db.IsForeignKeyViolation(err) {
+			return errUnknownQuestionInSet
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// questionSetResp loads the ordered questions of a question set.
+func questionSetResp(ctx context.Context, orm *db.Queries, questionSet db.QuestionSet) (QuestionSetResp, error) {
+	questions, err := orm.GetQuestionSetQuestions(ctx, questionSet.ID)
+	if err != nil {
+		return QuestionSetResp{}, err
+	}
+
+	if questions == nil {
+		questions = []db.Question{}
+	}
+
+	return QuestionSetResp{QuestionSet: questionSet, Questions: questions}, nil
+}
+
+func (svc *ApiV1Service) CreateQuestionSet(c *gin.Context) {
+	var in QuestionSetBody
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingFieldErrors(err)})
+		return
+	}
+
+	answerPolicy, err := in.answerPolicy()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := svc.conn.Begin(ctx)
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to begin transaction")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create question set"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	orm := db.New(svc.conn).WithTx(tx)
+	questionSet, err := orm.CreateQuestionSet(ctx, db.CreateQuestionSetParams{
+		LineageID:    uuid.New(),
+		Version:      1,
+		Title:        in.Title,
+		Description:  pgtype.Text{String: in.Description, Valid: in.Description != ""},
+		AnswerPolicy: answerPolicy,
+	})
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to create question set")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create question set"})
+		return
+	}
+
+	err = setQuestions(ctx, orm, questionSet.ID, in.QuestionIDs)
+	if errors.Is(err, errDuplicateQuestion) || errors.Is(err, errUnknownQuestionInSet) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to add questions to question set")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create question set"})
+		return
+	}
+
+	resp, err := questionSetResp(ctx, orm, questionSet)
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch question set questions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create question set"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to commit question set")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create question set"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+type GetQuestionSetsQuery struct {
+	LineageID string `form:"lineage_id"`
+	Limit     int    `form:"limit"`
+	Offset    int    `form:"offset"`
+}
+
+type GetQuestionSetsResp struct {
+	QuestionSets []db.QuestionSet `json:"question_sets"`
+}
+
+func (svc *ApiV1Service) GetQuestionSets(c *gin.Context) {
+	var query GetQuestionSetsQuery
+	if err := c.BindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters"})
+		return
+	}
+
+	if query.Limit == 0 {
+		query.Limit = 10
+	}
+
+	orm := db.New(svc.conn)
+	var questionSets []db.QuestionSet
+	var err error
+
+	if query.LineageID == "" {
+		questionSets, err = orm.GetQuestionSets(c.Request.Context(), db.GetQuestionSetsParams{
+			Limit:  int32(query.Limit),
+			Offset: int32(query.Offset),
+		})
+	} else {
+		lineageID, parseErr := uuid.Parse(query.LineageID)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters"})
+			return
+		}
+
+		questionSets, err = orm.GetQuestionSetsByLineageID(c.Request.Context(), db.GetQuestionSetsByLineageIDParams{
+			LineageID: lineageID,
+			Limit:     int32(query.Limit),
+			Offset:    int32(query.Offset),
+		})
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch question sets")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch question sets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, GetQuestionSetsResp{QuestionSets: questionSets})
+}
+
+func (svc *ApiV1Service) GetQuestionSet(c *gin.Context) {
+	questionSetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid question set ID"})
+		return
+	}
+
+	orm := db.New(svc.conn)
+	questionSet, err := internal.ResolveQuestionSet(c.Request.Context(), orm, questionSetID)
+	if errors.Is(err, internal.ErrQuestionSetNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "question set not found"})
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch question set")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch question set"})
+		return
+	}
+
+	resp, err := questionSetResp(c.Request.Context(), orm, questionSet)
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch question set questions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch question set"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// changeQuestionSet locks the question set named in the path and hands it to
+// change inside a transaction, replying with the set change returns along
+// with its questions.
+func (svc *ApiV1Service) changeQuestionSet(
+	c *gin.Context,
+	successStatus int,
+	change func(ctx context.Context, orm *db.Queries, questionSet db.QuestionSet) (db.QuestionSet, error),
+) {
+	questionSetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid question set ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := svc.conn.Begin(ctx)
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to begin transaction")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update question set"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	orm := db.New(svc.conn).WithTx(tx)
+	questionSet, err := orm.GetQuestionSetByIDForUpdate(ctx, questionSetID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "question set not found"})
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch question set")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update question set"})
+		return
+	}
+
+	questionSet, err = change(ctx, orm, questionSet)
+	switch {
+	case errors.Is(err, errQuestionSetNotDraft),
+		errors.Is(err, errQuestionSetEmpty),
+		errors.Is(err, errQuestionSetTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errDuplicateQuestion),
+		errors.Is(err, errUnknownQuestionInSet):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		svc.logger.Err(err).Ctx(c).Msg("failed to update question set")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update question set"})
+		return
+	}
+
+	resp, err := questionSetResp(ctx, orm, questionSet)
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch question set questions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update question set"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to commit question set")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update question set"})
+		return
+	}
+
+	c.JSON(successStatus, resp)
+}
+
+func (svc *ApiV1Service) UpdateQuestionSet(c *gin.Context) {
+	var in QuestionSetBody
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingFieldErrors(err)})
+		return
+	}
+
+	answerPolicy, err := in.answerPolicy()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	svc.changeQuestionSet(c, http.StatusOK, func(ctx context.Context, orm *db.Queries, questionSet db.QuestionSet) (db.QuestionSet, error) {
+		if questionSet.Status != db.QuestionSetStatusDraft {
+			return db.QuestionSet{}, errQuestionSetNotDraft
+		}
+
+		questionSet, err := orm.UpdateQuestionSetByID(ctx, db.UpdateQuestionSetByIDParams{
+			ID:           questionSet.ID,
+			Title:        in.Title,
+			Description:  pgtype.Text{String: in.Description, Valid: in.Description != ""},
+			AnswerPolicy: answerPolicy,
+		})
+		if err != nil {
+			return db.QuestionSet{}, err
+		}
+
+		err = setQuestions(ctx, orm, questionSet.ID, in.QuestionIDs)
+		if err != nil {
+			return db.QuestionSet{}, err
+		}
+
+		// Rules on questions that were taken out of the set go with them.
+		_, err = orm.DeleteStaleQuestionSetRules(ctx, questionSet.ID)
+		return questionSet, err
+	})
+}
+
+func (svc *ApiV1Service) PublishQuestionSet(c *gin.Context) {
+	svc.changeQuestionSet(c, http.StatusOK, func(ctx context.Context, orm *db.Queries, questionSet db.QuestionSet) (db.QuestionSet, error) {
+		if questionSet.Status != db.QuestionSetStatusDraft {
+			return db.QuestionSet{}, errQuestionSetTransition
+		}
+
+		questions, err := orm.GetQuestionSetQuestions(ctx, questionSet.ID)
+		if err != nil {
+			return db.QuestionSet{}, err
+		}
+
+		if len(questions) == 0 {
+			return db.QuestionSet{}, errQuestionSetEmpty
+		}
+
+		return orm.PublishQuestionSet(ctx, questionSet.ID)
+	})
+}
+
+func (svc *ApiV1Service) ArchiveQuestionSet(c *gin.Context) {
+	svc.changeQuestionSet(c, http.StatusOK, func(ctx context.Context, orm *db.Queries, questionSet db.QuestionSet) (db.QuestionSet, error) {
+		if questionSet.Status != db.QuestionSetStatusPublished {
+			return db.QuestionSet{}, errQuestionSetTransition
+		}
+
+		return orm.UpdateQuestionSetStatus(ctx, db.UpdateQuestionSetStatusParams{
+			ID:     questionSet.ID,
+			Status: db.QuestionSetStatusArchived,
+		})
+	})
+}
+
+// CreateQuestionSetVersion copies a question set with its questions and rules
+// into a new draft that carries the next version number of the same lineage.
+func (svc *ApiV1Service) CreateQuestionSetVersion(c *gin.Context) {
+	svc.changeQuestionSet(c, http.StatusCreated, func(ctx context.Context, orm *db.Queries, questionSet db.QuestionSet) (db.QuestionSet, error) {
+		latest, err := orm.GetLatestQuestionSetVersion(ctx, questionSet.LineageID)
+		if err != nil {
+			return db.QuestionSet{}, err
+		}
+
+		questions, err := orm.GetQuestionSetQuestions(ctx, questionSet.ID)
+		if err != nil {
+			return db.QuestionSet{}, err
+		}
+
+		draft, err := orm.CreateQuestionSet(ctx, db.CreateQuestionSetParams{
+			LineageID:    questionSet.LineageID,
+			Version:      latest + 1,
+			Title:        questionSet.Title,
+			Description:  questionSet.Description,
+			AnswerPolicy: questionSet.AnswerPolicy,
+		})
+		if err != nil {
+			return db.QuestionSet{}, err
+		}
+
+		questionIDs := make([]uuid.UUID, 0, len(questions))
+		for _, question := range questions {
+			questionIDs = append(questionIDs, question.ID)
+		}
+
+		err = setQuestions(ctx, orm, draft.ID, questionIDs)
+		if err != nil {
+			return db.QuestionSet{}, err
+		}
+
+		rules, err := orm.GetQuestionSetRules(ctx, questionSet.ID)
+		if err != nil {
+			return db.QuestionSet{}, err
+		}
+
+		ruleInputs := make([]internal.RuleInput, 0, len(rules))
+		for _, rule := range rules {
+			ruleInputs = append(ruleInputs, internal.RuleInput{
+				QuestionID:       rule.QuestionID,
+				Operator:         rule.Operator,
+				Value:            rule.Value,
+				TargetQuestionID: rule.TargetQuestionID,
+			})
+		}
+
+		_, err = setRules(ctx, orm, draft.ID, ruleInputs)
+		return draft, err
+	})
+}
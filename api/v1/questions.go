@@ -0,0 +1,285 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+	"github.com/zero-shubham/surveysvc/internal"
+)
+
+type QuestionBody struct {
+	QuestionType db.QuestionType `json:"question_type" binding:"required"`
+	Prompt       string          `json:"prompt" binding:"required"`
+	Options      []string        `json:"options"`
+	Required     bool            `json:"required"`
+	MinValue     *float64        `json:"min_value"`
+	MaxValue     *float64        `json:"max_value"`
+	MaxLength    *int32          `json:"max_length"`
+}
+
+func float8(f *float64) pgtype.Float8 {
+	if f == nil {
+		return pgtype.Float8{}
+	}
+
+	return pgtype.Float8{Float64: *f, Valid: true}
+}
+
+func int4(i *int32) pgtype.Int4 {
+	if i == nil {
+		return pgtype.Int4{}
+	}
+
+	return pgtype.Int4{Int32: *i, Valid: true}
+}
+
+// validate checks that the constraints fit the question type: choice
+// questions need a non-empty list of distinct options and every other type
+// takes none, only ratings and numbers have a range and only free text has a
+// maximum length.
+func (in *QuestionBody) validate() error {
+	if !in.QuestionType.Valid() {
+		return errors.New("unknown question type")
+	}
+
+	hasRange := in.QuestionType == db.QuestionTypeRating || in.QuestionType == db.QuestionTypeNumeric
+	if !hasRange && (in.MinValue != nil || in.MaxValue != nil) {
+		return errors.New("min_value and max_value are only allowed on rating and numeric questions")
+	}
+
+	if in.MinValue != nil && in.MaxValue != nil && *in.MinValue > *in.MaxValue {
+		return errors.New("min_value must not be greater than max_value")
+	}
+
+	if in.MaxLength != nil {
+		if in.QuestionType != db.QuestionTypeFreeText {
+			return errors.New("max_length is only allowed on free_text questions")
+		}
+		if *in.MaxLength <= 0 {
+			return errors.New("max_length must be positive")
+		}
+	}
+
+	if !internal.HasOptions(in.QuestionType) {
+		if len(in.Options) > 0 {
+			return errors.New("options are only allowed on choice questions")
+		}
+		in.Options = []string{}
+		return nil
+	}
+
+	if len(in.Options) == 0 {
+		return errors.New("choice questions need at least one option")
+	}
+
+	seen := make(map[string]struct{}, len(in.Options))
+	for _, option := range in.Options {
+		if option == "" {
+			return errors.New("options must not be empty")
+		}
+		if utf8.RuneCountInString(option) > internal.MaxOptionLength {
+			return fmt.Errorf("options must be at most %d characters", internal.MaxOptionLength)
+		}
+		if _, ok := seen[option]; ok {
+			return errors.New("options must be unique")
+		}
+		seen[option] = struct{}{}
+	}
+
+	return nil
+}
+
+func (svc *ApiV1Service) CreateQuestion(c *gin.Context) {
+	var in QuestionBody
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingFieldErrors(err)})
+		return
+	}
+
+	if err := in.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	orm := db.New(svc.conn)
+	question, err := orm.CreateQuestion(c.Request.Context(), db.CreateQuestionParams{
+		QuestionType: in.QuestionType,
+		Prompt:       in.Prompt,
+		Options:      in.Options,
+		Required:     in.Required,
+		MinValue:     float8(in.MinValue),
+		MaxValue:     float8(in.MaxValue),
+		MaxLength:    int4(in.MaxLength),
+	})
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to create question")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create question"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, question)
+}
+
+type GetQuestionsQuery struct {
+	Limit  int `form:"limit"`
+	Offset int `form:"offset"`
+}
+
+type GetQuestionsResp struct {
+	Questions []db.Question `json:"questions"`
+}
+
+func (svc *ApiV1Service) GetQuestions(c *gin.Context) {
+	var query GetQuestionsQuery
+	if err := c.BindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters"})
+		return
+	}
+
+	if query.Limit == 0 {
+		query.Limit = 10
+	}
+
+	orm := db.New(svc.conn)
+	questions, err := orm.GetQuestions(c.Request.Context(), db.GetQuestionsParams{
+		Limit:  int32(query.Limit),
+		Offset: int32(query.Offset),
+	})
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch questions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch questions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, GetQuestionsResp{Questions: questions})
+}
+
+func (svc *ApiV1Service) GetQuestion(c *gin.Context) {
+	questionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid question ID"})
+		return
+	}
+
+	orm := db.New(svc.conn)
+	question, err := internal.ResolveQuestion(c.Request.Context(), orm, questionID)
+	if errors.Is(err, internal.ErrQuestionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "question not found"})
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch question")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch question"})
+		return
+	}
+
+	c.JSON(http.StatusOK, question)
+}
+
+func (svc *ApiV1Service) UpdateQuestion(c *gin.Context) {
+	questionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid question ID"})
+		return
+	}
+
+	var in QuestionBody
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingFieldErrors(err)})
+		return
+	}
+
+	if err := in.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := svc.conn.Begin(ctx)
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to begin transaction")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update question"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	orm := db.New(svc.conn).WithTx(tx)
+
+	// Published and archived versions of a question set must keep asking
+	// exactly what respondents answered; changes go into a new version.
+	statuses, err := orm.GetQuestionSetStatusesByQuestionID(ctx, questionID)
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch question sets of question")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update question"})
+		return
+	}
+
+	for _, status := range statuses {
+		if status != db.QuestionSetStatusDraft {
+			c.JSON(http.StatusConflict, gin.H{"error": "question belongs to a published question set"})
+			return
+		}
+	}
+
+	question, err := orm.UpdateQuestionByID(ctx, db.UpdateQuestionByIDParams{
+		ID:           questionID,
+		QuestionType: in.QuestionType,
+		Prompt:       in.Prompt,
+		Options:      in.Options,
+		Required:     in.Required,
+		MinValue:     float8(in.MinValue),
+		MaxValue:     float8(in.MaxValue),
+		MaxLength:    int4(in.MaxLength),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "question not found"})
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to update question")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update question"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to commit question")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update question"})
+		return
+	}
+
+	c.JSON(http.StatusOK, question)
+}
+
+func (svc *ApiV1Service) DeleteQuestion(c *gin.Context) {
+	questionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid question ID"})
+		return
+	}
+
+	orm := db.New(svc.conn)
+	deleted, err := orm.DeleteQuestionByID(c.Request.Context(), questionID)
+	if db.IsForeignKeyViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "question is used by a question set"})
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to delete question")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete question"})
+		return
+	}
+
+	if deleted == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "question not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
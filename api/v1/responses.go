@@ -0,0 +1,152 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+	"github.com/zero-shubham/surveysvc/internal"
+)
+
+type StartResponseBody struct {
+	UserID        uuid.UUID     `json:"user_id" binding:"required"`
+	QuestionSetID uuid.UUID     `json:"question_set_id" binding:"required"`
+	CampaignID    uuid.NullUUID `json:"campaign_id"`
+}
+
+type ResponseResp struct {
+	db.Response
+	Answers []db.Answer `json:"answers"`
+}
+
+// StartResponse opens a response, or resumes the one the user already has in
+// progress for the question set and campaign, replying 201 and 200
+// respectively.
+func (svc *ApiV1Service) StartResponse(c *gin.Context) {
+	var in StartResponseBody
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingFieldErrors(err)})
+		return
+	}
+
+	response, started, err := svc.service.StartResponse(c.Request.Context(), internal.ResponseInput{
+		UserID:        in.UserID,
+		QuestionSetID: in.QuestionSetID,
+		CampaignID:    in.CampaignID,
+	})
+	if abortWithServiceError(c, err) {
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to start response")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start response"})
+		return
+	}
+
+	if !started {
+		svc.respondWithAnswers(c, response)
+		return
+	}
+
+	c.JSON(http.StatusCreated, ResponseResp{Response: response, Answers: []db.Answer{}})
+}
+
+// GetResponse returns a response with the answers given so far, for clients
+// resuming it.
+func (svc *ApiV1Service) GetResponse(c *gin.Context) {
+	responseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid response ID"})
+		return
+	}
+
+	response, err := internal.ResolveResponse(c.Request.Context(), db.New(svc.conn), responseID)
+	if errors.Is(err, internal.ErrResponseNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "response not found"})
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch response")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch response"})
+		return
+	}
+
+	svc.respondWithAnswers(c, response)
+}
+
+func (svc *ApiV1Service) respondWithAnswers(c *gin.Context, response db.Response) {
+	answers, err := db.New(svc.conn).GetAnswersByResponseID(c.Request.Context(), uuid.NullUUID{UUID: response.ID, Valid: true})
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch response answers")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch response"})
+		return
+	}
+
+	if answers == nil {
+		answers = []db.Answer{}
+	}
+
+	c.JSON(http.StatusOK, ResponseResp{Response: response, Answers: answers})
+}
+
+// SubmitResponse completes a response once every required question has been
+// answered.
+func (svc *ApiV1Service) SubmitResponse(c *gin.Context) {
+	responseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid response ID"})
+		return
+	}
+
+	response, err := svc.service.CompleteResponse(c.Request.Context(), responseID)
+	if errors.Is(err, internal.ErrResponseNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "response not found"})
+		return
+	}
+	if abortWithServiceError(c, err) {
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to submit response")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to submit response"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+type NextQuestionResp struct {
+	Complete bool         `json:"complete"`
+	Question *db.Question `json:"question"`
+}
+
+// GetNextQuestion evaluates the branching rules of the response's question
+// set against its answers and returns the question to ask next, or reports
+// that the response is complete.
+func (svc *ApiV1Service) GetNextQuestion(c *gin.Context) {
+	responseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid response ID"})
+		return
+	}
+
+	question, ok, err := svc.service.NextQuestion(c.Request.Context(), responseID)
+	if errors.Is(err, internal.ErrResponseNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "response not found"})
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to evaluate next question")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to evaluate next question"})
+		return
+	}
+
+	if !ok {
+		c.JSON(http.StatusOK, NextQuestionResp{Complete: true})
+		return
+	}
+
+	c.JSON(http.StatusOK, NextQuestionResp{Question: &question})
+}
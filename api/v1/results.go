@@ -0,0 +1,112 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/zero-shubham/surveysvc/internal"
+)
+
+// nullUUID parses an optional UUID query parameter.
+func nullUUID(raw string) (uuid.NullUUID, error) {
+	if raw == "" {
+		return uuid.NullUUID{}, nil
+	}
+
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+
+	return uuid.NullUUID{UUID: id, Valid: true}, nil
+}
+
+type GetQuestionResultsQuery struct {
+	CampaignID string    `form:"campaign_id"`
+	OrgID      string    `form:"org_id"`
+	From       time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To         time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// GetQuestionResults reports how many responses a question got, from how many
+// respondents, and how often each option was chosen. The answers counted can
+// be narrowed to a campaign, an org, and a range of RFC 3339 times, from
+// inclusive and to exclusive.
+func (svc *ApiV1Service) GetQuestionResults(c *gin.Context) {
+	questionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid question ID"})
+		return
+	}
+
+	var query GetQuestionResultsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters"})
+		return
+	}
+
+	var fields []internal.FieldError
+	campaignID, err := nullUUID(query.CampaignID)
+	if err != nil {
+		fields = append(fields, internal.FieldError{Field: "campaign_id", Message: "must be a UUID"})
+	}
+
+	orgID, err := nullUUID(query.OrgID)
+	if err != nil {
+		fields = append(fields, internal.FieldError{Field: "org_id", Message: "must be a UUID"})
+	}
+
+	if !query.From.IsZero() && !query.To.IsZero() && !query.To.After(query.From) {
+		fields = append(fields, internal.FieldError{Field: "to", Message: "must be after from"})
+	}
+
+	if len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters", "fields": fields})
+		return
+	}
+
+	results, err := svc.service.QuestionResults(c.Request.Context(), questionID, internal.ResultsFilter{
+		CampaignID: campaignID,
+		OrgID:      orgID,
+		From:       query.From,
+		To:         query.To,
+	})
+	if errors.Is(err, internal.ErrQuestionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "question not found"})
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to compute question results")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch question results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// GetCampaignSummary reports, for a campaign as a whole and for each question
+// mapped to it, how many responses were given, by how many respondents, when
+// the first and last were given, and how often each option was chosen.
+func (svc *ApiV1Service) GetCampaignSummary(c *gin.Context) {
+	campaignID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid campaign ID"})
+		return
+	}
+
+	summary, err := svc.service.CampaignSummary(c.Request.Context(), campaignID)
+	if errors.Is(err, internal.ErrCampaignNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "campaign not found"})
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to summarise campaign")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch campaign summary"})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,266 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+	"github.com/zero-shubham/surveysvc/internal"
+)
+
+// WebhookResp is a webhook as it is shown after it was created, without its
+// secret.
+type WebhookResp struct {
+	ID         uuid.UUID          `json:"id"`
+	OrgID      uuid.UUID          `json:"org_id"`
+	URL        string             `json:"url"`
+	EventTypes []string           `json:"event_types"`
+	Active     bool               `json:"active"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+}
+
+func webhookResp(webhook db.Webhook) WebhookResp {
+	return WebhookResp{
+		ID:         webhook.ID,
+		OrgID:      webhook.OrgID,
+		URL:        webhook.Url,
+		EventTypes: webhook.EventTypes,
+		Active:     webhook.Active,
+		CreatedAt:  webhook.CreatedAt,
+		UpdatedAt:  webhook.UpdatedAt,
+	}
+}
+
+type CreateWebhookBody struct {
+	OrgID      uuid.UUID `json:"org_id" binding:"required"`
+	URL        string    `json:"url" binding:"required"`
+	Secret     string    `json:"secret"`
+	EventTypes []string  `json:"event_types" binding:"required"`
+}
+
+// CreateWebhookResp is the only response that includes the secret deliveries
+// are signed with.
+type CreateWebhookResp struct {
+	WebhookResp
+	Secret string `json:"secret"`
+}
+
+func (svc *ApiV1Service) CreateWebhook(c *gin.Context) {
+	var in CreateWebhookBody
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingFieldErrors(err)})
+		return
+	}
+
+	webhook, err := svc.service.CreateWebhook(c.Request.Context(), internal.WebhookInput{
+		OrgID:      in.OrgID,
+		URL:        in.URL,
+		Secret:     in.Secret,
+		EventTypes: in.EventTypes,
+	})
+	if abortWithServiceError(c, err) {
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to create webhook")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create webhook"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateWebhookResp{WebhookResp: webhookResp(webhook), Secret: webhook.Secret})
+}
+
+type GetWebhooksQuery struct {
+	OrgID string `form:"org_id" binding:"required"`
+}
+
+type GetWebhooksResp struct {
+	Webhooks []WebhookResp `json:"webhooks"`
+}
+
+func (svc *ApiV1Service) GetWebhooks(c *gin.Context) {
+	var query GetWebhooksQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters"})
+		return
+	}
+
+	orgID, err := uuid.Parse(query.OrgID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters"})
+		return
+	}
+
+	webhooks, err := svc.service.ListWebhooks(c.Request.Context(), orgID)
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch webhooks")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch webhooks"})
+		return
+	}
+
+	resp := GetWebhooksResp{Webhooks: make([]WebhookResp, len(webhooks))}
+	for i, webhook := range webhooks {
+		resp.Webhooks[i] = webhookResp(webhook)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (svc *ApiV1Service) GetWebhook(c *gin.Context) {
+	webhookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook ID"})
+		return
+	}
+
+	webhook, err := svc.service.GetWebhook(c.Request.Context(), webhookID)
+	if errors.Is(err, internal.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch webhook")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhookResp(webhook))
+}
+
+type UpdateWebhookBody struct {
+	URL        string   `json:"url" binding:"required"`
+	EventTypes []string `json:"event_types" binding:"required"`
+	Active     *bool    `json:"active" binding:"required"`
+}
+
+func (svc *ApiV1Service) UpdateWebhook(c *gin.Context) {
+	webhookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook ID"})
+		return
+	}
+
+	var in UpdateWebhookBody
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingFieldErrors(err)})
+		return
+	}
+
+	webhook, err := svc.service.UpdateWebhook(c.Request.Context(), webhookID, internal.WebhookChange{
+		URL:        in.URL,
+		EventTypes: in.EventTypes,
+		Active:     *in.Active,
+	})
+	if errors.Is(err, internal.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		return
+	}
+	if abortWithServiceError(c, err) {
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to update webhook")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhookResp(webhook))
+}
+
+func (svc *ApiV1Service) DeleteWebhook(c *gin.Context) {
+	webhookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook ID"})
+		return
+	}
+
+	err = svc.service.DeleteWebhook(c.Request.Context(), webhookID)
+	if errors.Is(err, internal.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to delete webhook")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete webhook"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type GetWebhookDeliveriesQuery struct {
+	Status db.WebhookDeliveryStatus `form:"status"`
+	Limit  int                      `form:"limit"`
+}
+
+// WebhookDeliveryResp is a delivery with its payload shown as the JSON it was
+// sent as.
+type WebhookDeliveryResp struct {
+	ID             uuid.UUID                `json:"id"`
+	WebhookID      uuid.UUID                `json:"webhook_id"`
+	EventType      string                   `json:"event_type"`
+	Payload        json.RawMessage          `json:"payload"`
+	Status         db.WebhookDeliveryStatus `json:"status"`
+	Attempts       int32                    `json:"attempts"`
+	ResponseStatus pgtype.Int4              `json:"response_status"`
+	LastError      pgtype.Text              `json:"last_error"`
+	NextAttemptAt  pgtype.Timestamptz       `json:"next_attempt_at"`
+	CreatedAt      pgtype.Timestamptz       `json:"created_at"`
+	DeliveredAt    pgtype.Timestamptz       `json:"delivered_at"`
+}
+
+type GetWebhookDeliveriesResp struct {
+	Deliveries []WebhookDeliveryResp `json:"deliveries"`
+}
+
+// GetWebhookDeliveries lists the latest deliveries of a webhook, with the
+// status each was answered with and the last error of those that failed.
+func (svc *ApiV1Service) GetWebhookDeliveries(c *gin.Context) {
+	webhookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook ID"})
+		return
+	}
+
+	var query GetWebhookDeliveriesQuery
+	if err := c.ShouldBindQuery(&query); err != nil || (query.Status != "" && !query.Status.Valid()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters"})
+		return
+	}
+
+	status := db.NullWebhookDeliveryStatus{WebhookDeliveryStatus: query.Status, Valid: query.Status != ""}
+	deliveries, err := svc.service.WebhookDeliveries(c.Request.Context(), webhookID, status, query.Limit)
+	if errors.Is(err, internal.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		return
+	}
+	if err != nil {
+		svc.logger.Err(err).Ctx(c).Msg("failed to fetch webhook deliveries")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch webhook deliveries"})
+		return
+	}
+
+	resp := GetWebhookDeliveriesResp{Deliveries: make([]WebhookDeliveryResp, len(deliveries))}
+	for i, d := range deliveries {
+		resp.Deliveries[i] = WebhookDeliveryResp{
+			ID:             d.ID,
+			WebhookID:      d.WebhookID,
+			EventType:      d.EventType,
+			Payload:        d.Payload,
+			Status:         d.Status,
+			Attempts:       d.Attempts,
+			ResponseStatus: d.ResponseStatus,
+			LastError:      d.LastError,
+			NextAttemptAt:  d.NextAttemptAt,
+			CreatedAt:      d.CreatedAt,
+			DeliveredAt:    d.DeliveredAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"os"
 	"os/signal"
+	"strconv"
+	"time"
 
 	"github.com/exaring/otelpgx"
 	"github.com/jackc/pgx/v5"
@@ -12,18 +14,35 @@ import (
 	"github.com/rs/zerolog/log"
 	pgxUUID "github.com/vgarvardt/pgx-google-uuid/v5"
 	"github.com/zero-shubham/surveysvc/config"
+	"github.com/zero-shubham/surveysvc/events"
 	"github.com/zero-shubham/surveysvc/internal"
 	"github.com/zero-shubham/surveysvc/transport/messaging"
 )
 
 const (
-	MaxIdelConn           = 5
-	DbUrlEnv              = "DATABASE_URL"
-	KafkaBrokerEnv        = "KAFKA_BROKER_URI"
-	KafkaTopicConsumeEnv  = "KAFKA_CONSUMER_TOPIC"
-	KafkaDeadLetterEnv    = "KAFKA_DEADLETTER_TOPIC"
-	KafkaConsumerGroupEnv = "KAFKA_CONSUMER_GROUP"
-	OtelCollectorEnv      = "OLTP_HTTP_ENDPOINT"
+	MaxIdelConn            = 5
+	DbUrlEnv               = "DATABASE_URL"
+	KafkaBrokerEnv         = "KAFKA_BROKER_URI"
+	KafkaTopicConsumeEnv   = "KAFKA_CONSUMER_TOPIC"
+	KafkaDeadLetterEnv     = "KAFKA_DEADLETTER_TOPIC"
+	KafkaConsumerGroupEnv  = "KAFKA_CONSUMER_GROUP"
+	KafkaResponseTopicEnv  = "KAFKA_RESPONSE_TOPIC"
+	KafkaResponseDLQEnv    = "KAFKA_RESPONSE_DEADLETTER_TOPIC"
+	KafkaStartedDLQEnv     = "KAFKA_RESPONSE_STARTED_DEADLETTER_TOPIC"
+	KafkaCompletedDLQEnv   = "KAFKA_RESPONSE_COMPLETED_DEADLETTER_TOPIC"
+	KafkaAbandonedDLQEnv   = "KAFKA_RESPONSE_ABANDONED_DEADLETTER_TOPIC"
+	KafkaMappingTopicEnv   = "KAFKA_QUESTION_MAPPING_TOPIC"
+	KafkaMappingDLQEnv     = "KAFKA_QUESTION_MAPPING_DEADLETTER_TOPIC"
+	KafkaUserTopicEnv      = "KAFKA_USER_TOPIC"
+	KafkaUserDLQEnv        = "KAFKA_USER_DEADLETTER_TOPIC"
+	KafkaUserErasureDLQEnv = "KAFKA_USER_ERASURE_DEADLETTER_TOPIC"
+	KafkaBatchSizeEnv      = "KAFKA_BATCH_SIZE"
+	KafkaBatchWaitEnv      = "KAFKA_BATCH_WAIT_MS"
+	KafkaRouteByKeyEnv     = "KAFKA_ROUTE_BY_KEY"
+	KafkaDrainTimeoutEnv   = "KAFKA_DRAIN_TIMEOUT_MS"
+	DefaultBatchWait       = 100 * time.Millisecond
+	SchemaRegistryDirEnv   = "SCHEMA_REGISTRY_DIR"
+	OtelCollectorEnv       = "OLTP_HTTP_ENDPOINT"
 )
 
 func main() {
@@ -71,18 +90,85 @@ func main() {
 		log.Fatal().Err(err).Msg("failed to add metrics to db")
 	}
 
-	svc := internal.NewService(config.GetLogger(), dbConn)
-	consumer := messaging.NewKafkaConsumer(
+	// Payloads are decoded with the schemas the consumer is built with unless
+	// SCHEMA_REGISTRY_DIR points at a directory of schemas to use instead.
+	var svcOpts []internal.ServiceOption
+	if dir := os.Getenv(SchemaRegistryDirEnv); dir != "" {
+		schemas, err := events.NewFileRegistry(os.DirFS(dir))
+		if err != nil {
+			log.Fatal().Err(err).Str("dir", dir).Msg("failed to read schema registry")
+		}
+		svcOpts = append(svcOpts, internal.WithSchemaRegistry(schemas))
+	}
+
+	svc := internal.NewService(config.GetLogger(), dbConn, svcOpts...)
+
+	// Consumers get KAFKA_DRAIN_TIMEOUT_MS to finish in-flight messages on
+	// shutdown, messaging.DefaultDrainTimeout if it is not set.
+	var commonOpts []messaging.ConsumerOption
+	if ms, err := strconv.Atoi(os.Getenv(KafkaDrainTimeoutEnv)); err == nil && ms > 0 {
+		commonOpts = append(commonOpts, messaging.WithDrainTimeout(time.Duration(ms)*time.Millisecond))
+	}
+
+	// Answers are written one message at a time unless KAFKA_BATCH_SIZE asks
+	// for batches.
+	answerOpts := append([]messaging.ConsumerOption{}, commonOpts...)
+	if batchSize, _ := strconv.Atoi(os.Getenv(KafkaBatchSizeEnv)); batchSize > 1 {
+		batchWait := DefaultBatchWait
+		if ms, err := strconv.Atoi(os.Getenv(KafkaBatchWaitEnv)); err == nil && ms > 0 {
+			batchWait = time.Duration(ms) * time.Millisecond
+		}
+
+		answerOpts = append(answerOpts, messaging.WithBatching(svc.HandleAnswers, min(batchSize, internal.MaxBatchAnswers), batchWait))
+	}
+
+	if routeByKey, _ := strconv.ParseBool(os.Getenv(KafkaRouteByKeyEnv)); routeByKey {
+		answerOpts = append(answerOpts, messaging.WithKeyRouting())
+	}
+
+	router := messaging.NewRouter(
 		[]string{os.Getenv(KafkaBrokerEnv)},
-		os.Getenv(KafkaTopicConsumeEnv),
 		os.Getenv(KafkaConsumerGroupEnv)+os.Getenv(messaging.PodNameEnv),
-		svc.HandleAnswer,
-		os.Getenv(KafkaDeadLetterEnv),
 		config.GetLogger(),
 		tp,
 	)
-	consumer.Start(ctx, 2, mp)
+
+	router.Handle(os.Getenv(KafkaTopicConsumeEnv), svc.HandleAnswer, os.Getenv(KafkaDeadLetterEnv), answerOpts...)
+
+	// Response events are routed by their event-type header, falling back to
+	// the event_type of the body for producers that do not set it. Each type
+	// is dead lettered to its own topic, or to the response topic's if that
+	// is not set.
+	if responseTopic := os.Getenv(KafkaResponseTopicEnv); responseTopic != "" {
+		responseOpts := append([]messaging.ConsumerOption{}, commonOpts...)
+		for eventType, dlqEnv := range map[string]string{
+			internal.ResponseStartedEvent:   KafkaStartedDLQEnv,
+			internal.ResponseCompletedEvent: KafkaCompletedDLQEnv,
+			internal.ResponseAbandonedEvent: KafkaAbandonedDLQEnv,
+		} {
+			responseOpts = append(responseOpts, messaging.WithEventHandler(eventType, svc.ResponseEventHandler(eventType), os.Getenv(dlqEnv)))
+		}
+
+		router.Handle(responseTopic, svc.HandleResponseEvent, os.Getenv(KafkaResponseDLQEnv), responseOpts...)
+	}
+
+	if userTopic := os.Getenv(KafkaUserTopicEnv); userTopic != "" {
+		userOpts := append([]messaging.ConsumerOption{}, commonOpts...)
+		userOpts = append(userOpts, messaging.WithEventHandler(internal.UserErasureRequestedEvent, svc.HandleUserErasure, os.Getenv(KafkaUserErasureDLQEnv)))
+
+		router.Handle(userTopic, nil, os.Getenv(KafkaUserDLQEnv), userOpts...)
+	}
+
+	if mappingTopic := os.Getenv(KafkaMappingTopicEnv); mappingTopic != "" {
+		router.Handle(mappingTopic, svc.HandleQuestionMappingChange, os.Getenv(KafkaMappingDLQEnv), commonOpts...)
+	}
+
+	router.Start(ctx, 2, mp)
 
 	<-ctx.Done()
 
+	// Wait for the consumers to drain before the tracer and meter are shut
+	// down and the process exits.
+	<-router.Done()
+	log.Info().Msg("consumers stopped")
 }
@@ -0,0 +1,258 @@
+// Command dlq-replay lists the messages on a dead letter topic and publishes
+// them back to the topic they were consumed from.
+//
+//	dlq-replay list   -topic answers-dlq -error "connection reset"
+//	dlq-replay replay -topic answers-dlq -since 2025-01-02T00:00:00Z -rate 50
+//
+// The dead letter topic is read from its start up to its end at the time the
+// command runs, without joining a consumer group, so running it again
+// replays the same messages unless the filters exclude them.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	"github.com/zero-shubham/surveysvc/transport/messaging"
+)
+
+const (
+	KafkaBrokerEnv     = "KAFKA_BROKER_URI"
+	KafkaDeadLetterEnv = "KAFKA_DEADLETTER_TOPIC"
+)
+
+type filter struct {
+	sourceTopic   string
+	errorContains string
+	consumerGroup string
+	since         time.Time
+	until         time.Time
+	limit         int
+}
+
+func (f filter) match(dl messaging.DeadLetter) bool {
+	if f.sourceTopic != "" && dl.Topic != f.sourceTopic {
+		return false
+	}
+
+	if f.errorContains != "" && !strings.Contains(dl.Error, f.errorContains) {
+		return false
+	}
+
+	if f.consumerGroup != "" && dl.ConsumerGroup != f.consumerGroup {
+		return false
+	}
+
+	if !f.since.IsZero() && dl.LastFailureAt.Before(f.since) {
+		return false
+	}
+
+	if !f.until.IsZero() && !dl.LastFailureAt.Before(f.until) {
+		return false
+	}
+
+	return true
+}
+
+type listedMessage struct {
+	Partition int    `json:"partition"`
+	Offset    int64  `json:"offset"`
+	Key       string `json:"key,omitempty"`
+	messaging.DeadLetter
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s list|replay [flags]\n", os.Args[0])
+	os.Exit(2)
+}
+
+func main() {
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	command := os.Args[1]
+	if command != "list" && command != "replay" {
+		usage()
+	}
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	broker := flags.String("broker", os.Getenv(KafkaBrokerEnv), "kafka broker address")
+	topic := flags.String("topic", os.Getenv(KafkaDeadLetterEnv), "dead letter topic to read")
+	sourceTopic := flags.String("source-topic", "", "only messages consumed from this topic")
+	errorContains := flags.String("error", "", "only messages whose error contains this text")
+	consumerGroup := flags.String("group", "", "only messages that failed in this consumer group")
+	since := flags.String("since", "", "only messages that last failed at or after this RFC 3339 time")
+	until := flags.String("until", "", "only messages that last failed before this RFC 3339 time")
+	limit := flags.Int("limit", 0, "stop after this many matching messages, 0 for no limit")
+	rate := flags.Float64("rate", 0, "replay at most this many messages per second, 0 for no limit")
+	dryRun := flags.Bool("dry-run", false, "list what replay would publish without publishing it")
+	flags.Parse(os.Args[2:])
+
+	if *broker == "" || *topic == "" {
+		log.Fatal().Msg("both -broker and -topic are required")
+	}
+
+	f := filter{
+		sourceTopic:   *sourceTopic,
+		errorContains: *errorContains,
+		consumerGroup: *consumerGroup,
+		limit:         *limit,
+	}
+
+	var err error
+	if *since != "" {
+		if f.since, err = time.Parse(time.RFC3339, *since); err != nil {
+			log.Fatal().Err(err).Msg("invalid -since")
+		}
+	}
+	if *until != "" {
+		if f.until, err = time.Parse(time.RFC3339, *until); err != nil {
+			log.Fatal().Err(err).Msg("invalid -until")
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var writer *kafka.Writer
+	if command == "replay" && !*dryRun {
+		// The topic is set per message, from the dead letter headers.
+		writer = &kafka.Writer{
+			Addr:     kafka.TCP(*broker),
+			Balancer: &kafka.Hash{},
+		}
+		defer writer.Close()
+	}
+
+	var throttle <-chan time.Time
+	if *rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / *rate))
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	out := json.NewEncoder(os.Stdout)
+	matched := 0
+	err = readDeadLetters(ctx, *broker, *topic, func(m kafka.Message, dl messaging.DeadLetter) (bool, error) {
+		if !f.match(dl) {
+			return true, nil
+		}
+		matched++
+
+		if err := out.Encode(listedMessage{Partition: m.Partition, Offset: m.Offset, Key: string(m.Key), DeadLetter: dl}); err != nil {
+			return false, err
+		}
+
+		if writer != nil {
+			if throttle != nil {
+				select {
+				case <-ctx.Done():
+					return false, ctx.Err()
+				case <-throttle:
+				}
+			}
+
+			err := writer.WriteMessages(ctx, kafka.Message{
+				Topic:   dl.Topic,
+				Key:     m.Key,
+				Value:   m.Value,
+				Headers: messaging.WithoutDeadLetterHeaders(m.Headers),
+			})
+			if err != nil {
+				return false, err
+			}
+		}
+
+		return f.limit == 0 || matched < f.limit, nil
+	})
+	if err != nil {
+		log.Fatal().Err(err).Int("matched", matched).Msg("failed to read dead letters")
+	}
+
+	log.Info().Str("command", command).Bool("dry_run", *dryRun).Int("matched", matched).Msg("done")
+}
+
+// readDeadLetters hands every dead letter on topic, up to the end of each
+// partition when called, to visit until visit returns false. Messages without
+// dead letter headers are skipped since there is no telling where they go.
+func readDeadLetters(ctx context.Context, broker string, topic string, visit func(kafka.Message, messaging.DeadLetter) (bool, error)) error {
+	conn, err := kafka.DialContext(ctx, "tcp", broker)
+	if err != nil {
+		return err
+	}
+	partitions, err := conn.ReadPartitions(topic)
+	conn.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, partition := range partitions {
+		leader, err := kafka.DialLeader(ctx, "tcp", broker, topic, partition.ID)
+		if err != nil {
+			return err
+		}
+		first, last, err := leader.ReadOffsets()
+		leader.Close()
+		if err != nil {
+			return err
+		}
+
+		if first >= last {
+			continue
+		}
+
+		more, err := readPartition(ctx, broker, topic, partition.ID, first, last, visit)
+		if err != nil || !more {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readPartition(ctx context.Context, broker string, topic string, partition int, first int64, last int64, visit func(kafka.Message, messaging.DeadLetter) (bool, error)) (bool, error) {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   []string{broker},
+		Topic:     topic,
+		Partition: partition,
+		MaxBytes:  10e6,
+	})
+	defer reader.Close()
+
+	if err := reader.SetOffset(first); err != nil {
+		return false, err
+	}
+
+	for {
+		m, err := reader.ReadMessage(ctx)
+		if err != nil {
+			return false, err
+		}
+
+		dl, ok := messaging.ParseDeadLetter(&m)
+		if !ok {
+			log.Warn().Int("partition", partition).Int64("offset", m.Offset).Msg("skipping message without dead letter headers")
+		} else {
+			more, err := visit(m, dl)
+			if err != nil || !more {
+				return false, err
+			}
+		}
+
+		if m.Offset >= last-1 {
+			return true, nil
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/exaring/otelpgx"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	pgxUUID "github.com/vgarvardt/pgx-google-uuid/v5"
+	"github.com/zero-shubham/surveysvc/config"
+	"github.com/zero-shubham/surveysvc/internal"
+)
+
+const (
+	MaxIdelConn              = 5
+	KafkaBrokerEnv           = "KAFKA_BROKER_URI"
+	OutboxAnswerTopicEnv     = "OUTBOX_ANSWER_TOPIC"
+	OutboxResponseTopicEnv   = "OUTBOX_RESPONSE_TOPIC"
+	OutboxQuestionMappingEnv = "OUTBOX_QUESTION_MAPPING_TOPIC"
+	OutboxBatchSizeEnv       = "OUTBOX_BATCH_SIZE"
+	OutboxPollIntervalEnv    = "OUTBOX_POLL_INTERVAL_MS"
+	OutboxRetentionEnv       = "OUTBOX_RETENTION_HOURS"
+	DefaultBatchSize         = 100
+	DefaultPollInterval      = time.Second
+	DefaultRetention         = 7 * 24 * time.Hour
+	OtelCollectorEnv         = "OLTP_HTTP_ENDPOINT"
+)
+
+func main() {
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer stop()
+
+	tp, mp, err := config.Init(ctx, os.Getenv(OtelCollectorEnv), config.ServiceName+"-relay")
+	if err != nil {
+		log.Fatal().Err(err).Send()
+	}
+	defer func() {
+		if err := tp.Shutdown(context.Background()); err != nil {
+			log.Err(err).Msg("error shutting down tracer provider")
+		}
+
+		if err := mp.Shutdown(context.Background()); err != nil {
+			log.Err(err).Msg("error shutting down metric provider")
+		}
+
+		log.Info().Msg("shutdown of tracer and meter complete")
+	}()
+
+	dbConfig, err := pgxpool.ParseConfig(os.Getenv(config.DbUrlEnv))
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create a config")
+	}
+
+	dbConfig.ConnConfig.Tracer = otelpgx.NewTracer(otelpgx.WithTracerProvider(tp))
+	dbConfig.MaxConns = MaxIdelConn
+	dbConfig.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		pgxUUID.Register(conn.TypeMap())
+		return nil
+	}
+
+	dbConn, err := pgxpool.NewWithConfig(ctx, dbConfig)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to db conn")
+	}
+	defer dbConn.Close()
+
+	// Only event types with a topic configured are published; the others
+	// stay in the outbox until one is.
+	topics := map[string]string{}
+	if topic := os.Getenv(OutboxAnswerTopicEnv); topic != "" {
+		topics[internal.AnswerCreatedEvent] = topic
+		topics[internal.AnswerUpdatedEvent] = topic
+	}
+	if topic := os.Getenv(OutboxResponseTopicEnv); topic != "" {
+		topics[internal.ResponseCompletedEvent] = topic
+	}
+	if topic := os.Getenv(OutboxQuestionMappingEnv); topic != "" {
+		topics[internal.QuestionMappingUpdatedEvent] = topic
+	}
+	if len(topics) == 0 {
+		log.Fatal().Msg("no outbox topics configured")
+	}
+
+	batchSize := DefaultBatchSize
+	if n, err := strconv.Atoi(os.Getenv(OutboxBatchSizeEnv)); err == nil && n > 0 {
+		batchSize = n
+	}
+
+	interval := DefaultPollInterval
+	if ms, err := strconv.Atoi(os.Getenv(OutboxPollIntervalEnv)); err == nil && ms > 0 {
+		interval = time.Duration(ms) * time.Millisecond
+	}
+
+	retention := DefaultRetention
+	if hours, err := strconv.Atoi(os.Getenv(OutboxRetentionEnv)); err == nil && hours >= 0 {
+		retention = time.Duration(hours) * time.Hour
+	}
+
+	// Messages of one aggregate share a key, and so a partition, which keeps
+	// them in order. The topic is set per message.
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(os.Getenv(KafkaBrokerEnv)),
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}
+	defer writer.Close()
+
+	relay := internal.NewRelay(config.GetLogger(), dbConn, writer, topics, batchSize)
+
+	log.Info().Int("batch_size", batchSize).Dur("interval", interval).Msg("starting outbox relay")
+	relay.Run(ctx, interval, retention)
+	log.Info().Msg("outbox relay stopped")
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"os"
+	"os/signal"
+	"time"
 
 	"github.com/exaring/otelpgx"
 	"github.com/jackc/pgx/v5"
@@ -12,18 +14,24 @@ import (
 	pgxUUID "github.com/vgarvardt/pgx-google-uuid/v5"
 	"github.com/zero-shubham/surveysvc/api"
 	"github.com/zero-shubham/surveysvc/config"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+	"github.com/zero-shubham/surveysvc/internal"
 	"go.opentelemetry.io/otel"
 )
 
 const (
-	MaxIdelConn      = 5
-	OtelCollectorEnv = "OLTP_HTTP_ENDPOINT"
+	MaxIdelConn       = 5
+	OtelCollectorEnv  = "OLTP_HTTP_ENDPOINT"
+	SchedulerInterval = time.Minute
+	WebhookInterval   = 5 * time.Second
+	WebhookBatchSize  = 50
 )
 
 func main() {
 	zerolog.SetGlobalLevel(zerolog.InfoLevel)
 
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer stop()
 
 	tp, mp, err := config.Init(ctx, os.Getenv(OtelCollectorEnv), config.ServiceName)
 	if err != nil {
@@ -64,7 +72,22 @@ func main() {
 		log.Fatal().Err(err).Msg("failed to add metrics to db")
 	}
 
-	api.NewRouter(config.GetLogger(), dbConn).Start(ctx, tp, mp)
+	go internal.NewService(config.GetLogger(), dbConn).RunCampaignScheduler(ctx, SchedulerInterval)
+	go internal.NewWebhookDispatcher(config.GetLogger(), db.New(dbConn), nil, internal.DefaultWebhookRetryPolicy, WebhookBatchSize).Run(ctx, WebhookInterval)
+
+	// Answers written by this process and by the consumer reach the live
+	// streams through Postgres notifications. The server shuts down only once
+	// the feed has stopped and closed the streams, which would otherwise keep
+	// their connections open past the shutdown timeout.
+	feed := internal.NewAnswerFeed(config.GetLogger(), dbConn)
+	go feed.Run(ctx, dbConn)
+
+	router := api.NewRouter(config.GetLogger(), dbConn, internal.WithAnswerFeed(feed)).ShutdownAfter(feed.Done())
+	router.Start(ctx, tp, mp)
 	<-ctx.Done()
 
+	// Wait for the server to shut down before the tracer and meter are shut
+	// down and the process exits.
+	<-router.Done()
+	log.Info().Msg("server stopped")
 }
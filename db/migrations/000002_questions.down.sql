@@ -0,0 +1,3 @@
+DROP TABLE questions;
+
+DROP TYPE question_type;
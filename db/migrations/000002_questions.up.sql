@@ -0,0 +1,20 @@
+CREATE TYPE question_type AS ENUM (
+    'single_choice',
+    'multi_choice',
+    'free_text',
+    'rating',
+    'numeric',
+    'date'
+);
+
+-- Create questions table
+CREATE TABLE questions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    question_type question_type NOT NULL,
+    prompt TEXT NOT NULL,
+    options TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_questions_question_type ON questions(question_type);
@@ -0,0 +1,5 @@
+DROP TABLE question_set_questions;
+
+DROP TABLE question_sets;
+
+DROP TYPE question_set_status;
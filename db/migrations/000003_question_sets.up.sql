@@ -0,0 +1,32 @@
+CREATE TYPE question_set_status AS ENUM (
+    'draft',
+    'published',
+    'archived'
+);
+
+-- Create question_sets table, every version of a set is its own row and
+-- versions of the same set share a lineage_id
+CREATE TABLE question_sets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    lineage_id UUID NOT NULL,
+    version INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    status question_set_status NOT NULL DEFAULT 'draft',
+    published_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (lineage_id, version)
+);
+
+-- Create question_set_questions table holding the ordered membership of a set
+CREATE TABLE question_set_questions (
+    question_set_id UUID NOT NULL REFERENCES question_sets(id) ON DELETE CASCADE,
+    question_id UUID NOT NULL REFERENCES questions(id),
+    position INTEGER NOT NULL,
+    PRIMARY KEY (question_set_id, question_id),
+    UNIQUE (question_set_id, position)
+);
+
+CREATE INDEX idx_question_sets_status ON question_sets(status);
+CREATE INDEX idx_question_set_questions_question_id ON question_set_questions(question_id);
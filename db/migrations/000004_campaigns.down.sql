@@ -0,0 +1,5 @@
+ALTER TABLE answers DROP COLUMN campaign_id;
+
+DROP TABLE campaigns;
+
+DROP TYPE campaign_status;
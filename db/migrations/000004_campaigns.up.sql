@@ -0,0 +1,26 @@
+CREATE TYPE campaign_status AS ENUM (
+    'draft',
+    'scheduled',
+    'live',
+    'paused',
+    'closed'
+);
+
+-- Create campaigns table
+CREATE TABLE campaigns (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    status campaign_status NOT NULL DEFAULT 'draft',
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    CHECK (starts_at IS NULL OR ends_at IS NULL OR ends_at > starts_at)
+);
+
+ALTER TABLE answers ADD COLUMN campaign_id UUID;
+
+CREATE INDEX idx_campaigns_org_id ON campaigns(org_id);
+CREATE INDEX idx_campaigns_status ON campaigns(status);
+CREATE INDEX idx_answers_campaign_id ON answers(campaign_id);
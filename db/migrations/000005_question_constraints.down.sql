@@ -0,0 +1,7 @@
+ALTER TABLE questions
+    DROP CONSTRAINT questions_max_length,
+    DROP CONSTRAINT questions_value_range,
+    DROP COLUMN max_length,
+    DROP COLUMN max_value,
+    DROP COLUMN min_value,
+    DROP COLUMN required;
@@ -0,0 +1,7 @@
+ALTER TABLE questions
+    ADD COLUMN required BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN min_value DOUBLE PRECISION,
+    ADD COLUMN max_value DOUBLE PRECISION,
+    ADD COLUMN max_length INTEGER,
+    ADD CONSTRAINT questions_value_range CHECK (min_value IS NULL OR max_value IS NULL OR min_value <= max_value),
+    ADD CONSTRAINT questions_max_length CHECK (max_length IS NULL OR max_length > 0);
@@ -0,0 +1,7 @@
+ALTER TABLE answers ADD COLUMN selected_options TEXT[] NOT NULL DEFAULT '{}';
+
+UPDATE answers
+SET selected_options = ARRAY[selected_option]
+WHERE selected_option IS NOT NULL;
+
+CREATE INDEX idx_answers_selected_options ON answers USING GIN (selected_options);
@@ -0,0 +1,5 @@
+ALTER TABLE answers DROP COLUMN response_id;
+
+DROP TABLE responses;
+
+DROP TYPE response_status;
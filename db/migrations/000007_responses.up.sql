@@ -0,0 +1,31 @@
+CREATE TYPE response_status AS ENUM (
+    'in_progress',
+    'completed',
+    'abandoned'
+);
+
+-- Create responses table, a response groups the answers a user gives while
+-- going through a question set
+CREATE TABLE responses (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    question_set_id UUID NOT NULL REFERENCES question_sets(id),
+    campaign_id UUID REFERENCES campaigns(id),
+    status response_status NOT NULL DEFAULT 'in_progress',
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+ALTER TABLE answers ADD COLUMN response_id UUID REFERENCES responses(id);
+
+-- A user has at most one open response per question set and campaign
+CREATE UNIQUE INDEX idx_responses_in_progress ON responses(
+    user_id,
+    question_set_id,
+    COALESCE(campaign_id, '00000000-0000-0000-0000-000000000000')
+) WHERE status = 'in_progress';
+
+CREATE INDEX idx_responses_question_set_id ON responses(question_set_id);
+CREATE INDEX idx_responses_campaign_id ON responses(campaign_id);
+CREATE INDEX idx_answers_response_id ON answers(response_id);
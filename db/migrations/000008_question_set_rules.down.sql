@@ -0,0 +1,3 @@
+DROP TABLE question_set_rules;
+
+DROP TYPE rule_operator;
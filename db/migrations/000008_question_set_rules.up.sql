@@ -0,0 +1,21 @@
+CREATE TYPE rule_operator AS ENUM (
+    'equals',
+    'not_equals',
+    'includes',
+    'greater_than',
+    'less_than'
+);
+
+-- Create question_set_rules table, when the answer to question_id satisfies
+-- the rule the flow jumps to target_question_id, or ends if it is NULL
+CREATE TABLE question_set_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    question_set_id UUID NOT NULL REFERENCES question_sets(id) ON DELETE CASCADE,
+    question_id UUID NOT NULL REFERENCES questions(id),
+    operator rule_operator NOT NULL,
+    value TEXT NOT NULL,
+    target_question_id UUID REFERENCES questions(id),
+    position INTEGER NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (question_set_id, position)
+);
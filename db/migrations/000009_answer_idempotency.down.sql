@@ -0,0 +1,3 @@
+DROP INDEX idx_answers_idempotency_key;
+
+ALTER TABLE answers DROP COLUMN idempotency_key;
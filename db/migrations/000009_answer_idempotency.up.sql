@@ -0,0 +1,5 @@
+-- Replays of the same submission carry the same key and resolve to the answer
+-- that was stored first
+ALTER TABLE answers ADD COLUMN idempotency_key TEXT;
+
+CREATE UNIQUE INDEX idx_answers_idempotency_key ON answers(idempotency_key);
@@ -0,0 +1,6 @@
+DROP INDEX idx_answers_user_question;
+
+ALTER TABLE campaigns DROP COLUMN answer_policy;
+ALTER TABLE question_sets DROP COLUMN answer_policy;
+
+DROP TYPE answer_policy;
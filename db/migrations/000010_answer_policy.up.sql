@@ -0,0 +1,12 @@
+CREATE TYPE answer_policy AS ENUM (
+    'allow_multiple',
+    'keep_latest',
+    'reject_duplicates'
+);
+
+-- How repeated answers by a user to the same question are handled. Campaigns
+-- may override the policy of the question set they run.
+ALTER TABLE question_sets ADD COLUMN answer_policy answer_policy NOT NULL DEFAULT 'allow_multiple';
+ALTER TABLE campaigns ADD COLUMN answer_policy answer_policy;
+
+CREATE INDEX idx_answers_user_question ON answers(user_id, question_id, question_set_id);
@@ -0,0 +1,4 @@
+DROP TABLE webhook_deliveries;
+DROP TABLE webhooks;
+
+DROP TYPE webhook_delivery_status;
@@ -0,0 +1 @@
+DROP INDEX idx_answers_campaign_question;
@@ -0,0 +1,2 @@
+-- Serves the live tallies of a campaign's answers, per question.
+CREATE INDEX idx_answers_campaign_question ON answers(campaign_id, question_id);
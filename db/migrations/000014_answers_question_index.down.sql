@@ -0,0 +1 @@
+DROP INDEX idx_answers_question_created_at;
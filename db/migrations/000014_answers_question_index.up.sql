@@ -0,0 +1,3 @@
+-- Serves the results of a question, which are usually asked for over a range
+-- of dates.
+CREATE INDEX idx_answers_question_created_at ON answers(question_id, created_at);
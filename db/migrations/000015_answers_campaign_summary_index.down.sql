@@ -0,0 +1,2 @@
+CREATE INDEX idx_answers_campaign_question ON answers(campaign_id, question_id);
+DROP INDEX idx_answers_campaign_question_summary;
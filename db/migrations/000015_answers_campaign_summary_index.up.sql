@@ -0,0 +1,4 @@
+-- Lets campaign summaries count respondents and find the first and last
+-- answers from the index alone, without visiting every answer row.
+CREATE INDEX idx_answers_campaign_question_summary ON answers(campaign_id, question_id) INCLUDE (user_id, created_at);
+DROP INDEX idx_answers_campaign_question;
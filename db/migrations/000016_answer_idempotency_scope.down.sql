@@ -0,0 +1,5 @@
+DROP INDEX idx_answers_user_idempotency_key;
+
+CREATE UNIQUE INDEX idx_answers_idempotency_key ON answers(idempotency_key);
+
+ALTER TABLE answers DROP COLUMN idempotency_fingerprint;
@@ -0,0 +1,8 @@
+-- Idempotency keys are chosen by clients, so they only identify a submission
+-- within the answers of the same user. The fingerprint of what was submitted
+-- lets a replay that carries a different answer be told apart.
+ALTER TABLE answers ADD COLUMN idempotency_fingerprint TEXT;
+
+DROP INDEX idx_answers_idempotency_key;
+
+CREATE UNIQUE INDEX idx_answers_user_idempotency_key ON answers(user_id, idempotency_key);
@@ -0,0 +1 @@
+CREATE INDEX idx_answers_campaign_id ON answers(campaign_id);
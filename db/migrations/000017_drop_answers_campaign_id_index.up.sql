@@ -0,0 +1,4 @@
+-- idx_answers_campaign_question_summary leads with campaign_id, so it serves
+-- every lookup of a campaign's answers, and its included columns let the
+-- campaign totals skip the answer rows as well.
+DROP INDEX idx_answers_campaign_id;
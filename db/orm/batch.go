@@ -0,0 +1,271 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: batch.go
+
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrBatchAlreadyClosed = errors.New("batch already closed")
+)
+
+const createAnswerBatch = `-- name: CreateAnswerBatch :batchone
+INSERT INTO
+  answers (
+    selected_option,
+    answer_text,
+    user_id,
+    question_id,
+    question_set_id,
+    campaign_id,
+    selected_options,
+    response_id,
+    idempotency_key,
+    idempotency_fingerprint
+  )
+VALUES
+  ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+  ON CONFLICT (user_id, idempotency_key) DO NOTHING
+  RETURNING id, selected_option, answer_text, user_id, question_id, question_set_id, created_at, updated_at, campaign_id, selected_options, response_id, idempotency_key, idempotency_fingerprint
+`
+
+type CreateAnswerBatchBatchResults struct {
+	br     pgx.BatchResults
+	tot    int
+	closed bool
+}
+
+type CreateAnswerBatchParams struct {
+	SelectedOption         pgtype.Text   `json:"selected_option"`
+	AnswerText             pgtype.Text   `json:"answer_text"`
+	UserID                 uuid.UUID     `json:"user_id"`
+	QuestionID             uuid.UUID     `json:"question_id"`
+	QuestionSetID          uuid.UUID     `json:"question_set_id"`
+	CampaignID             uuid.NullUUID `json:"campaign_id"`
+	SelectedOptions        []string      `json:"selected_options"`
+	ResponseID             uuid.NullUUID `json:"response_id"`
+	IdempotencyKey         pgtype.Text   `json:"-"`
+	IdempotencyFingerprint pgtype.Text   `json:"-"`
+}
+
+func (q *Queries) CreateAnswerBatch(ctx context.Context, arg []CreateAnswerBatchParams) *CreateAnswerBatchBatchResults {
+	batch := &pgx.Batch{}
+	for _, a := range arg {
+		vals := []interface{}{
+			a.SelectedOption,
+			a.AnswerText,
+			a.UserID,
+			a.QuestionID,
+			a.QuestionSetID,
+			a.CampaignID,
+			a.SelectedOptions,
+			a.ResponseID,
+			a.IdempotencyKey,
+			a.IdempotencyFingerprint,
+		}
+		batch.Queue(createAnswerBatch, vals...)
+	}
+	br := q.db.SendBatch(ctx, batch)
+	return &CreateAnswerBatchBatchResults{br, len(arg), false}
+}
+
+func (b *CreateAnswerBatchBatchResults) QueryRow(f func(int, Answer, error)) {
+	defer b.br.Close()
+	for t := 0; t < b.tot; t++ {
+		var i Answer
+		if b.closed {
+			if f != nil {
+				f(t, i, ErrBatchAlreadyClosed)
+			}
+			continue
+		}
+		row := b.br.QueryRow()
+		err := row.Scan(
+			&i.ID,
+			&i.SelectedOption,
+			&i.AnswerText,
+			&i.UserID,
+			&i.QuestionID,
+			&i.QuestionSetID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CampaignID,
+			&i.SelectedOptions,
+			&i.ResponseID,
+			&i.IdempotencyKey,
+			&i.IdempotencyFingerprint,
+		)
+		if f != nil {
+			f(t, i, err)
+		}
+	}
+}
+
+func (b *CreateAnswerBatchBatchResults) Close() error {
+	b.closed = true
+	return b.br.Close()
+}
+
+const createOutboxEventBatch = `-- name: CreateOutboxEventBatch :batchexec
+INSERT INTO outbox (aggregate_type, aggregate_id, event_type, payload, headers)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+type CreateOutboxEventBatchBatchResults struct {
+	br     pgx.BatchResults
+	tot    int
+	closed bool
+}
+
+type CreateOutboxEventBatchParams struct {
+	AggregateType string    `json:"aggregate_type"`
+	AggregateID   uuid.UUID `json:"aggregate_id"`
+	EventType     string    `json:"event_type"`
+	Payload       []byte    `json:"payload"`
+	Headers       []byte    `json:"headers"`
+}
+
+func (q *Queries) CreateOutboxEventBatch(ctx context.Context, arg []CreateOutboxEventBatchParams) *CreateOutboxEventBatchBatchResults {
+	batch := &pgx.Batch{}
+	for _, a := range arg {
+		vals := []interface{}{
+			a.AggregateType,
+			a.AggregateID,
+			a.EventType,
+			a.Payload,
+			a.Headers,
+		}
+		batch.Queue(createOutboxEventBatch, vals...)
+	}
+	br := q.db.SendBatch(ctx, batch)
+	return &CreateOutboxEventBatchBatchResults{br, len(arg), false}
+}
+
+func (b *CreateOutboxEventBatchBatchResults) Exec(f func(int, error)) {
+	defer b.br.Close()
+	for t := 0; t < b.tot; t++ {
+		if b.closed {
+			if f != nil {
+				f(t, ErrBatchAlreadyClosed)
+			}
+			continue
+		}
+		_, err := b.br.Exec()
+		if f != nil {
+			f(t, err)
+		}
+	}
+}
+
+func (b *CreateOutboxEventBatchBatchResults) Close() error {
+	b.closed = true
+	return b.br.Close()
+}
+
+const enqueueWebhookDeliveriesBatch = `-- name: EnqueueWebhookDeliveriesBatch :batchexec
+INSERT INTO webhook_deliveries (webhook_id, event_type, payload)
+SELECT id, $1, $2
+FROM webhooks
+WHERE org_id = $3
+  AND active
+  AND $1::TEXT = ANY(event_types)
+`
+
+type EnqueueWebhookDeliveriesBatchBatchResults struct {
+	br     pgx.BatchResults
+	tot    int
+	closed bool
+}
+
+type EnqueueWebhookDeliveriesBatchParams struct {
+	EventType string    `json:"event_type"`
+	Payload   []byte    `json:"payload"`
+	OrgID     uuid.UUID `json:"org_id"`
+}
+
+func (q *Queries) EnqueueWebhookDeliveriesBatch(ctx context.Context, arg []EnqueueWebhookDeliveriesBatchParams) *EnqueueWebhookDeliveriesBatchBatchResults {
+	batch := &pgx.Batch{}
+	for _, a := range arg {
+		vals := []interface{}{
+			a.EventType,
+			a.Payload,
+			a.OrgID,
+		}
+		batch.Queue(enqueueWebhookDeliveriesBatch, vals...)
+	}
+	br := q.db.SendBatch(ctx, batch)
+	return &EnqueueWebhookDeliveriesBatchBatchResults{br, len(arg), false}
+}
+
+func (b *EnqueueWebhookDeliveriesBatchBatchResults) Exec(f func(int, error)) {
+	defer b.br.Close()
+	for t := 0; t < b.tot; t++ {
+		if b.closed {
+			if f != nil {
+				f(t, ErrBatchAlreadyClosed)
+			}
+			continue
+		}
+		_, err := b.br.Exec()
+		if f != nil {
+			f(t, err)
+		}
+	}
+}
+
+func (b *EnqueueWebhookDeliveriesBatchBatchResults) Close() error {
+	b.closed = true
+	return b.br.Close()
+}
+
+const notifyAnswerChangeBatch = `-- name: NotifyAnswerChangeBatch :batchexec
+SELECT pg_notify('answer_changes', $1::TEXT)
+`
+
+type NotifyAnswerChangeBatchBatchResults struct {
+	br     pgx.BatchResults
+	tot    int
+	closed bool
+}
+
+func (q *Queries) NotifyAnswerChangeBatch(ctx context.Context, payload []string) *NotifyAnswerChangeBatchBatchResults {
+	batch := &pgx.Batch{}
+	for _, a := range payload {
+		vals := []interface{}{
+			a,
+		}
+		batch.Queue(notifyAnswerChangeBatch, vals...)
+	}
+	br := q.db.SendBatch(ctx, batch)
+	return &NotifyAnswerChangeBatchBatchResults{br, len(payload), false}
+}
+
+func (b *NotifyAnswerChangeBatchBatchResults) Exec(f func(int, error)) {
+	defer b.br.Close()
+	for t := 0; t < b.tot; t++ {
+		if b.closed {
+			if f != nil {
+				f(t, ErrBatchAlreadyClosed)
+			}
+			continue
+		}
+		_, err := b.br.Exec()
+		if f != nil {
+			f(t, err)
+		}
+	}
+}
+
+func (b *NotifyAnswerChangeBatchBatchResults) Close() error {
+	b.closed = true
+	return b.br.Close()
+}
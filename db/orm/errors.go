@@ -0,0 +1,45 @@
+package db
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+
+	dataExceptionClass      = "22"
+	integrityViolationClass = "23"
+)
+
+// IsForeignKeyViolation reports whether err was raised by postgres because a
+// row references, or is referenced by, a row that does not allow it.
+func IsForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation
+}
+
+// IsUniqueViolation reports whether err was raised by postgres because a row
+// would duplicate a unique key.
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// IsIntegrityViolation reports whether err was raised by postgres because a
+// row would break a constraint of its table, such as a unique, foreign key or
+// check constraint.
+func IsIntegrityViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && strings.HasPrefix(pgErr.Code, integrityViolationClass)
+}
+
+// IsDataException reports whether err was raised by postgres because a value
+// could not be stored in or converted to its column type.
+func IsDataException(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && strings.HasPrefix(pgErr.Code, dataExceptionClass)
+}
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"encoding/json"
+)
+
+// MarshalJSON encodes an unset policy as null rather than as a struct, so a
+// campaign that inherits the policy of its question set reads naturally.
+func (ns NullAnswerPolicy) MarshalJSON() ([]byte, error) {
+	if !ns.Valid {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(ns.AnswerPolicy)
+}
+
+func (ns *NullAnswerPolicy) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		ns.AnswerPolicy, ns.Valid = "", false
+		return nil
+	}
+
+	if err := json.Unmarshal(data, &ns.AnswerPolicy); err != nil {
+		return err
+	}
+
+	ns.Valid = true
+	return nil
+}
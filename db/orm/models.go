@@ -5,19 +5,450 @@
 package db
 
 import (
+	"database/sql/driver"
+	"fmt"
+
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type AnswerPolicy string
+
+const (
+	AnswerPolicyAllowMultiple    AnswerPolicy = "allow_multiple"
+	AnswerPolicyKeepLatest       AnswerPolicy = "keep_latest"
+	AnswerPolicyRejectDuplicates AnswerPolicy = "reject_duplicates"
+)
+
+func (e *AnswerPolicy) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = AnswerPolicy(s)
+	case string:
+		*e = AnswerPolicy(s)
+	default:
+		return fmt.Errorf("unsupported scan type for AnswerPolicy: %T", src)
+	}
+	return nil
+}
+
+type NullAnswerPolicy struct {
+	AnswerPolicy AnswerPolicy `json:"answer_policy"`
+	Valid        bool         `json:"valid"` // Valid is true if AnswerPolicy is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullAnswerPolicy) Scan(value interface{}) error {
+	if value == nil {
+		ns.AnswerPolicy, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.AnswerPolicy.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullAnswerPolicy) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.AnswerPolicy), nil
+}
+
+func (e AnswerPolicy) Valid() bool {
+	switch e {
+	case AnswerPolicyAllowMultiple,
+		AnswerPolicyKeepLatest,
+		AnswerPolicyRejectDuplicates:
+		return true
+	}
+	return false
+}
+
+type CampaignStatus string
+
+const (
+	CampaignStatusDraft     CampaignStatus = "draft"
+	CampaignStatusScheduled CampaignStatus = "scheduled"
+	CampaignStatusLive      CampaignStatus = "live"
+	CampaignStatusPaused    CampaignStatus = "paused"
+	CampaignStatusClosed    CampaignStatus = "closed"
+)
+
+func (e *CampaignStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = CampaignStatus(s)
+	case string:
+		*e = CampaignStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for CampaignStatus: %T", src)
+	}
+	return nil
+}
+
+type NullCampaignStatus struct {
+	CampaignStatus CampaignStatus `json:"campaign_status"`
+	Valid          bool           `json:"valid"` // Valid is true if CampaignStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullCampaignStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.CampaignStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.CampaignStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullCampaignStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.CampaignStatus), nil
+}
+
+func (e CampaignStatus) Valid() bool {
+	switch e {
+	case CampaignStatusDraft,
+		CampaignStatusScheduled,
+		CampaignStatusLive,
+		CampaignStatusPaused,
+		CampaignStatusClosed:
+		return true
+	}
+	return false
+}
+
+type QuestionSetStatus string
+
+const (
+	QuestionSetStatusDraft     QuestionSetStatus = "draft"
+	QuestionSetStatusPublished QuestionSetStatus = "published"
+	QuestionSetStatusArchived  QuestionSetStatus = "archived"
+)
+
+func (e *QuestionSetStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = QuestionSetStatus(s)
+	case string:
+		*e = QuestionSetStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for QuestionSetStatus: %T", src)
+	}
+	return nil
+}
+
+type NullQuestionSetStatus struct {
+	QuestionSetStatus QuestionSetStatus `json:"question_set_status"`
+	Valid             bool              `json:"valid"` // Valid is true if QuestionSetStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullQuestionSetStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.QuestionSetStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.QuestionSetStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullQuestionSetStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.QuestionSetStatus), nil
+}
+
+func (e QuestionSetStatus) Valid() bool {
+	switch e {
+	case QuestionSetStatusDraft,
+		QuestionSetStatusPublished,
+		QuestionSetStatusArchived:
+		return true
+	}
+	return false
+}
+
+type QuestionType string
+
+const (
+	QuestionTypeSingleChoice QuestionType = "single_choice"
+	QuestionTypeMultiChoice  QuestionType = "multi_choice"
+	QuestionTypeFreeText     QuestionType = "free_text"
+	QuestionTypeRating       QuestionType = "rating"
+	QuestionTypeNumeric      QuestionType = "numeric"
+	QuestionTypeDate         QuestionType = "date"
+)
+
+func (e *QuestionType) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = QuestionType(s)
+	case string:
+		*e = QuestionType(s)
+	default:
+		return fmt.Errorf("unsupported scan type for QuestionType: %T", src)
+	}
+	return nil
+}
+
+type NullQuestionType struct {
+	QuestionType QuestionType `json:"question_type"`
+	Valid        bool         `json:"valid"` // Valid is true if QuestionType is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullQuestionType) Scan(value interface{}) error {
+	if value == nil {
+		ns.QuestionType, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.QuestionType.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullQuestionType) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.QuestionType), nil
+}
+
+func (e QuestionType) Valid() bool {
+	switch e {
+	case QuestionTypeSingleChoice,
+		QuestionTypeMultiChoice,
+		QuestionTypeFreeText,
+		QuestionTypeRating,
+		QuestionTypeNumeric,
+		QuestionTypeDate:
+		return true
+	}
+	return false
+}
+
+type ResponseStatus string
+
+const (
+	ResponseStatusInProgress ResponseStatus = "in_progress"
+	ResponseStatusCompleted  ResponseStatus = "completed"
+	ResponseStatusAbandoned  ResponseStatus = "abandoned"
+)
+
+func (e *ResponseStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ResponseStatus(s)
+	case string:
+		*e = ResponseStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ResponseStatus: %T", src)
+	}
+	return nil
+}
+
+type NullResponseStatus struct {
+	ResponseStatus ResponseStatus `json:"response_status"`
+	Valid          bool           `json:"valid"` // Valid is true if ResponseStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullResponseStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ResponseStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ResponseStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullResponseStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ResponseStatus), nil
+}
+
+func (e ResponseStatus) Valid() bool {
+	switch e {
+	case ResponseStatusInProgress,
+		ResponseStatusCompleted,
+		ResponseStatusAbandoned:
+		return true
+	}
+	return false
+}
+
+type RuleOperator string
+
+const (
+	RuleOperatorEquals      RuleOperator = "equals"
+	RuleOperatorNotEquals   RuleOperator = "not_equals"
+	RuleOperatorIncludes    RuleOperator = "includes"
+	RuleOperatorGreaterThan RuleOperator = "greater_than"
+	RuleOperatorLessThan    RuleOperator = "less_than"
+)
+
+func (e *RuleOperator) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = RuleOperator(s)
+	case string:
+		*e = RuleOperator(s)
+	default:
+		return fmt.Errorf("unsupported scan type for RuleOperator: %T", src)
+	}
+	return nil
+}
+
+type NullRuleOperator struct {
+	RuleOperator RuleOperator `json:"rule_operator"`
+	Valid        bool         `json:"valid"` // Valid is true if RuleOperator is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullRuleOperator) Scan(value interface{}) error {
+	if value == nil {
+		ns.RuleOperator, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.RuleOperator.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullRuleOperator) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.RuleOperator), nil
+}
+
+func (e RuleOperator) Valid() bool {
+	switch e {
+	case RuleOperatorEquals,
+		RuleOperatorNotEquals,
+		RuleOperatorIncludes,
+		RuleOperatorGreaterThan,
+		RuleOperatorLessThan:
+		return true
+	}
+	return false
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed"
+)
+
+func (e *WebhookDeliveryStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WebhookDeliveryStatus(s)
+	case string:
+		*e = WebhookDeliveryStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WebhookDeliveryStatus: %T", src)
+	}
+	return nil
+}
+
+type NullWebhookDeliveryStatus struct {
+	WebhookDeliveryStatus WebhookDeliveryStatus `json:"webhook_delivery_status"`
+	Valid                 bool                  `json:"valid"` // Valid is true if WebhookDeliveryStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullWebhookDeliveryStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.WebhookDeliveryStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.WebhookDeliveryStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullWebhookDeliveryStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.WebhookDeliveryStatus), nil
+}
+
+func (e WebhookDeliveryStatus) Valid() bool {
+	switch e {
+	case WebhookDeliveryStatusPending,
+		WebhookDeliveryStatusSucceeded,
+		WebhookDeliveryStatusFailed:
+		return true
+	}
+	return false
+}
+
 type Answer struct {
-	ID             uuid.UUID          `json:"id"`
-	SelectedOption pgtype.Text        `json:"selected_option"`
-	AnswerText     pgtype.Text        `json:"answer_text"`
-	UserID         uuid.UUID          `json:"user_id"`
-	QuestionID     uuid.UUID          `json:"question_id"`
-	QuestionSetID  uuid.UUID          `json:"question_set_id"`
-	CreatedAt      pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+	ID                     uuid.UUID          `json:"id"`
+	SelectedOption         pgtype.Text        `json:"selected_option"`
+	AnswerText             pgtype.Text        `json:"answer_text"`
+	UserID                 uuid.UUID          `json:"user_id"`
+	QuestionID             uuid.UUID          `json:"question_id"`
+	QuestionSetID          uuid.UUID          `json:"question_set_id"`
+	CreatedAt              pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt              pgtype.Timestamptz `json:"updated_at"`
+	CampaignID             uuid.NullUUID      `json:"campaign_id"`
+	SelectedOptions        []string           `json:"selected_options"`
+	ResponseID             uuid.NullUUID      `json:"response_id"`
+	IdempotencyKey         pgtype.Text        `json:"-"`
+	IdempotencyFingerprint pgtype.Text        `json:"-"`
+}
+
+type Campaign struct {
+	ID           uuid.UUID          `json:"id"`
+	OrgID        uuid.UUID          `json:"org_id"`
+	Name         string             `json:"name"`
+	Status       CampaignStatus     `json:"status"`
+	StartsAt     pgtype.Timestamptz `json:"starts_at"`
+	EndsAt       pgtype.Timestamptz `json:"ends_at"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	AnswerPolicy NullAnswerPolicy   `json:"answer_policy"`
+}
+
+type Outbox struct {
+	ID            int64              `json:"id"`
+	AggregateType string             `json:"aggregate_type"`
+	AggregateID   uuid.UUID          `json:"aggregate_id"`
+	EventType     string             `json:"event_type"`
+	Payload       []byte             `json:"payload"`
+	Headers       []byte             `json:"headers"`
+	Attempts      int32              `json:"attempts"`
+	LastError     pgtype.Text        `json:"last_error"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	SentAt        pgtype.Timestamptz `json:"sent_at"`
+}
+
+type Question struct {
+	ID           uuid.UUID          `json:"id"`
+	QuestionType QuestionType       `json:"question_type"`
+	Prompt       string             `json:"prompt"`
+	Options      []string           `json:"options"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	Required     bool               `json:"required"`
+	MinValue     pgtype.Float8      `json:"min_value"`
+	MaxValue     pgtype.Float8      `json:"max_value"`
+	MaxLength    pgtype.Int4        `json:"max_length"`
 }
 
 type QuestionMapping struct {
@@ -28,3 +459,69 @@ type QuestionMapping struct {
 	CreatedAt  pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
 }
+
+type QuestionSet struct {
+	ID           uuid.UUID          `json:"id"`
+	LineageID    uuid.UUID          `json:"lineage_id"`
+	Version      int32              `json:"version"`
+	Title        string             `json:"title"`
+	Description  pgtype.Text        `json:"description"`
+	Status       QuestionSetStatus  `json:"status"`
+	PublishedAt  pgtype.Timestamptz `json:"published_at"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	AnswerPolicy AnswerPolicy       `json:"answer_policy"`
+}
+
+type QuestionSetQuestion struct {
+	QuestionSetID uuid.UUID `json:"question_set_id"`
+	QuestionID    uuid.UUID `json:"question_id"`
+	Position      int32     `json:"position"`
+}
+
+type QuestionSetRule struct {
+	ID               uuid.UUID          `json:"id"`
+	QuestionSetID    uuid.UUID          `json:"question_set_id"`
+	QuestionID       uuid.UUID          `json:"question_id"`
+	Operator         RuleOperator       `json:"operator"`
+	Value            string             `json:"value"`
+	TargetQuestionID uuid.NullUUID      `json:"target_question_id"`
+	Position         int32              `json:"position"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+}
+
+type Response struct {
+	ID            uuid.UUID          `json:"id"`
+	UserID        uuid.UUID          `json:"user_id"`
+	QuestionSetID uuid.UUID          `json:"question_set_id"`
+	CampaignID    uuid.NullUUID      `json:"campaign_id"`
+	Status        ResponseStatus     `json:"status"`
+	StartedAt     pgtype.Timestamptz `json:"started_at"`
+	CompletedAt   pgtype.Timestamptz `json:"completed_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+}
+
+type Webhook struct {
+	ID         uuid.UUID          `json:"id"`
+	OrgID      uuid.UUID          `json:"org_id"`
+	Url        string             `json:"url"`
+	Secret     string             `json:"secret"`
+	EventTypes []string           `json:"event_types"`
+	Active     bool               `json:"active"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID             `json:"id"`
+	WebhookID      uuid.UUID             `json:"webhook_id"`
+	EventType      string                `json:"event_type"`
+	Payload        []byte                `json:"payload"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int32                 `json:"attempts"`
+	ResponseStatus pgtype.Int4           `json:"response_status"`
+	LastError      pgtype.Text           `json:"last_error"`
+	NextAttemptAt  pgtype.Timestamptz    `json:"next_attempt_at"`
+	CreatedAt      pgtype.Timestamptz    `json:"created_at"`
+	DeliveredAt    pgtype.Timestamptz    `json:"delivered_at"`
+}
@@ -12,6 +12,185 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const abandonResponse = `-- name: AbandonResponse :one
+UPDATE responses
+SET status = 'abandoned', updated_at = NOW()
+WHERE id = $1 RETURNING id, user_id, question_set_id, campaign_id, status, started_at, completed_at, updated_at
+`
+
+func (q *Queries) AbandonResponse(ctx context.Context, id uuid.UUID) (Response, error) {
+	row := q.db.QueryRow(ctx, abandonResponse, id)
+	var i Response
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.QuestionSetID,
+		&i.CampaignID,
+		&i.Status,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const addQuestionSetQuestion = `-- name: AddQuestionSetQuestion :exec
+INSERT INTO question_set_questions (question_set_id, question_id, position)
+VALUES ($1, $2, $3)
+`
+
+type AddQuestionSetQuestionParams struct {
+	QuestionSetID uuid.UUID `json:"question_set_id"`
+	QuestionID    uuid.UUID `json:"question_id"`
+	Position      int32     `json:"position"`
+}
+
+func (q *Queries) AddQuestionSetQuestion(ctx context.Context, arg AddQuestionSetQuestionParams) error {
+	_, err := q.db.Exec(ctx, addQuestionSetQuestion, arg.QuestionSetID, arg.QuestionID, arg.Position)
+	return err
+}
+
+const claimOutboxEvents = `-- name: ClaimOutboxEvents :many
+SELECT id, aggregate_type, aggregate_id, event_type, payload, headers, attempts, last_error, created_at, sent_at FROM outbox
+WHERE sent_at IS NULL
+  AND event_type = ANY($1::TEXT[])
+  AND attempts < $2
+ORDER BY id
+LIMIT $3
+FOR UPDATE SKIP LOCKED
+`
+
+type ClaimOutboxEventsParams struct {
+	EventTypes  []string `json:"event_types"`
+	MaxAttempts int32    `json:"max_attempts"`
+	MaxEvents   int32    `json:"max_events"`
+}
+
+func (q *Queries) ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]Outbox, error) {
+	rows, err := q.db.Query(ctx, claimOutboxEvents, arg.EventTypes, arg.MaxAttempts, arg.MaxEvents)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Outbox
+	for rows.Next() {
+		var i Outbox
+		if err := rows.Scan(
+			&i.ID,
+			&i.AggregateType,
+			&i.AggregateID,
+			&i.EventType,
+			&i.Payload,
+			&i.Headers,
+			&i.Attempts,
+			&i.LastError,
+			&i.CreatedAt,
+			&i.SentAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimWebhookDeliveries = `-- name: ClaimWebhookDeliveries :many
+WITH due AS (
+    SELECT webhook_deliveries.id FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE webhook_deliveries
+SET next_attempt_at = NOW() + make_interval(secs => $1::FLOAT8)
+FROM due, webhooks
+WHERE webhook_deliveries.id = due.id AND webhooks.id = webhook_deliveries.webhook_id
+RETURNING webhook_deliveries.id, webhook_deliveries.event_type, webhook_deliveries.payload, webhook_deliveries.attempts, webhook_deliveries.created_at, webhooks.url, webhooks.secret
+`
+
+type ClaimWebhookDeliveriesParams struct {
+	LeaseSeconds  float64 `json:"lease_seconds"`
+	MaxDeliveries int32   `json:"max_deliveries"`
+}
+
+type ClaimWebhookDeliveriesRow struct {
+	ID        uuid.UUID          `json:"id"`
+	EventType string             `json:"event_type"`
+	Payload   []byte             `json:"payload"`
+	Attempts  int32              `json:"attempts"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	Url       string             `json:"url"`
+	Secret    string             `json:"secret"`
+}
+
+func (q *Queries) ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]ClaimWebhookDeliveriesRow, error) {
+	rows, err := q.db.Query(ctx, claimWebhookDeliveries, arg.LeaseSeconds, arg.MaxDeliveries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimWebhookDeliveriesRow
+	for rows.Next() {
+		var i ClaimWebhookDeliveriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventType,
+			&i.Payload,
+			&i.Attempts,
+			&i.CreatedAt,
+			&i.Url,
+			&i.Secret,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const closeEndedCampaigns = `-- name: CloseEndedCampaigns :execrows
+UPDATE campaigns
+SET status = 'closed', updated_at = NOW()
+WHERE status IN ('scheduled', 'live', 'paused') AND ends_at <= NOW()
+`
+
+func (q *Queries) CloseEndedCampaigns(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, closeEndedCampaigns)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const completeResponse = `-- name: CompleteResponse :one
+UPDATE responses
+SET status = 'completed', completed_at = NOW(), updated_at = NOW()
+WHERE id = $1 RETURNING id, user_id, question_set_id, campaign_id, status, started_at, completed_at, updated_at
+`
+
+func (q *Queries) CompleteResponse(ctx context.Context, id uuid.UUID) (Response, error) {
+	row := q.db.QueryRow(ctx, completeResponse, id)
+	var i Response
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.QuestionSetID,
+		&i.CampaignID,
+		&i.Status,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const createAnswer = `-- name: CreateAnswer :one
 INSERT INTO
   answers (
@@ -19,19 +198,30 @@ INSERT INTO
     answer_text,
     user_id,
     question_id,
-    question_set_id
+    question_set_id,
+    campaign_id,
+    selected_options,
+    response_id,
+    idempotency_key,
+    idempotency_fingerprint
   )
 VALUES
-  ($1, $2, $3, $4, $5)
-  RETURNING id, selected_option, answer_text, user_id, question_id, question_set_id, created_at, updated_at
+  ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+  ON CONFLICT (user_id, idempotency_key) DO NOTHING
+  RETURNING id, selected_option, answer_text, user_id, question_id, question_set_id, created_at, updated_at, campaign_id, selected_options, response_id, idempotency_key, idempotency_fingerprint
 `
 
 type CreateAnswerParams struct {
-	SelectedOption pgtype.Text `json:"selected_option"`
-	AnswerText     pgtype.Text `json:"answer_text"`
-	UserID         uuid.UUID   `json:"user_id"`
-	QuestionID     uuid.UUID   `json:"question_id"`
-	QuestionSetID  uuid.UUID   `json:"question_set_id"`
+	SelectedOption         pgtype.Text   `json:"selected_option"`
+	AnswerText             pgtype.Text   `json:"answer_text"`
+	UserID                 uuid.UUID     `json:"user_id"`
+	QuestionID             uuid.UUID     `json:"question_id"`
+	QuestionSetID          uuid.UUID     `json:"question_set_id"`
+	CampaignID             uuid.NullUUID `json:"campaign_id"`
+	SelectedOptions        []string      `json:"selected_options"`
+	ResponseID             uuid.NullUUID `json:"response_id"`
+	IdempotencyKey         pgtype.Text   `json:"-"`
+	IdempotencyFingerprint pgtype.Text   `json:"-"`
 }
 
 func (q *Queries) CreateAnswer(ctx context.Context, arg CreateAnswerParams) (Answer, error) {
@@ -41,6 +231,11 @@ func (q *Queries) CreateAnswer(ctx context.Context, arg CreateAnswerParams) (Ans
 		arg.UserID,
 		arg.QuestionID,
 		arg.QuestionSetID,
+		arg.CampaignID,
+		arg.SelectedOptions,
+		arg.ResponseID,
+		arg.IdempotencyKey,
+		arg.IdempotencyFingerprint,
 	)
 	var i Answer
 	err := row.Scan(
@@ -52,6 +247,116 @@ func (q *Queries) CreateAnswer(ctx context.Context, arg CreateAnswerParams) (Ans
 		&i.QuestionSetID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CampaignID,
+		&i.SelectedOptions,
+		&i.ResponseID,
+		&i.IdempotencyKey,
+		&i.IdempotencyFingerprint,
+	)
+	return i, err
+}
+
+const createCampaign = `-- name: CreateCampaign :one
+INSERT INTO campaigns (org_id, name, status, starts_at, ends_at, answer_policy)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, org_id, name, status, starts_at, ends_at, created_at, updated_at, answer_policy
+`
+
+type CreateCampaignParams struct {
+	OrgID        uuid.UUID          `json:"org_id"`
+	Name         string             `json:"name"`
+	Status       CampaignStatus     `json:"status"`
+	StartsAt     pgtype.Timestamptz `json:"starts_at"`
+	EndsAt       pgtype.Timestamptz `json:"ends_at"`
+	AnswerPolicy NullAnswerPolicy   `json:"answer_policy"`
+}
+
+func (q *Queries) CreateCampaign(ctx context.Context, arg CreateCampaignParams) (Campaign, error) {
+	row := q.db.QueryRow(ctx, createCampaign,
+		arg.OrgID,
+		arg.Name,
+		arg.Status,
+		arg.StartsAt,
+		arg.EndsAt,
+		arg.AnswerPolicy,
+	)
+	var i Campaign
+	err := row.Scan(
+		&i.ID,
+		&i.OrgID,
+		&i.Name,
+		&i.Status,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.AnswerPolicy,
+	)
+	return i, err
+}
+
+const createOutboxEvent = `-- name: CreateOutboxEvent :exec
+INSERT INTO outbox (aggregate_type, aggregate_id, event_type, payload, headers)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+type CreateOutboxEventParams struct {
+	AggregateType string    `json:"aggregate_type"`
+	AggregateID   uuid.UUID `json:"aggregate_id"`
+	EventType     string    `json:"event_type"`
+	Payload       []byte    `json:"payload"`
+	Headers       []byte    `json:"headers"`
+}
+
+func (q *Queries) CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) error {
+	_, err := q.db.Exec(ctx, createOutboxEvent,
+		arg.AggregateType,
+		arg.AggregateID,
+		arg.EventType,
+		arg.Payload,
+		arg.Headers,
+	)
+	return err
+}
+
+const createQuestion = `-- name: CreateQuestion :one
+INSERT INTO questions (question_type, prompt, options, required, min_value, max_value, max_length)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, question_type, prompt, options, created_at, updated_at, required, min_value, max_value, max_length
+`
+
+type CreateQuestionParams struct {
+	QuestionType QuestionType  `json:"question_type"`
+	Prompt       string        `json:"prompt"`
+	Options      []string      `json:"options"`
+	Required     bool          `json:"required"`
+	MinValue     pgtype.Float8 `json:"min_value"`
+	MaxValue     pgtype.Float8 `json:"max_value"`
+	MaxLength    pgtype.Int4   `json:"max_length"`
+}
+
+func (q *Queries) CreateQuestion(ctx context.Context, arg CreateQuestionParams) (Question, error) {
+	row := q.db.QueryRow(ctx, createQuestion,
+		arg.QuestionType,
+		arg.Prompt,
+		arg.Options,
+		arg.Required,
+		arg.MinValue,
+		arg.MaxValue,
+		arg.MaxLength,
+	)
+	var i Question
+	err := row.Scan(
+		&i.ID,
+		&i.QuestionType,
+		&i.Prompt,
+		&i.Options,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Required,
+		&i.MinValue,
+		&i.MaxValue,
+		&i.MaxLength,
 	)
 	return i, err
 }
@@ -82,36 +387,1329 @@ func (q *Queries) CreateQuestionMapping(ctx context.Context, arg CreateQuestionM
 	return i, err
 }
 
-const getAnswers = `-- name: GetAnswers :many
-SELECT id, selected_option, answer_text, user_id, question_id, question_set_id, created_at, updated_at
-FROM answers
+const createQuestionSet = `-- name: CreateQuestionSet :one
+INSERT INTO question_sets (lineage_id, version, title, description, answer_policy)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, lineage_id, version, title, description, status, published_at, created_at, updated_at, answer_policy
+`
+
+type CreateQuestionSetParams struct {
+	LineageID    uuid.UUID    `json:"lineage_id"`
+	Version      int32        `json:"version"`
+	Title        string       `json:"title"`
+	Description  pgtype.Text  `json:"description"`
+	AnswerPolicy AnswerPolicy `json:"answer_policy"`
+}
+
+func (q *Queries) CreateQuestionSet(ctx context.Context, arg CreateQuestionSetParams) (QuestionSet, error) {
+	row := q.db.QueryRow(ctx, createQuestionSet,
+		arg.LineageID,
+		arg.Version,
+		arg.Title,
+		arg.Description,
+		arg.AnswerPolicy,
+	)
+	var i QuestionSet
+	err := row.Scan(
+		&i.ID,
+		&i.LineageID,
+		&i.Version,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.PublishedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.AnswerPolicy,
+	)
+	return i, err
+}
+
+const createQuestionSetRule = `-- name: CreateQuestionSetRule :one
+INSERT INTO question_set_rules (question_set_id, question_id, operator, value, target_question_id, position)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, question_set_id, question_id, operator, value, target_question_id, position, created_at
+`
+
+type CreateQuestionSetRuleParams struct {
+	QuestionSetID    uuid.UUID     `json:"question_set_id"`
+	QuestionID       uuid.UUID     `json:"question_id"`
+	Operator         RuleOperator  `json:"operator"`
+	Value            string        `json:"value"`
+	TargetQuestionID uuid.NullUUID `json:"target_question_id"`
+	Position         int32         `json:"position"`
+}
+
+func (q *Queries) CreateQuestionSetRule(ctx context.Context, arg CreateQuestionSetRuleParams) (QuestionSetRule, error) {
+	row := q.db.QueryRow(ctx, createQuestionSetRule,
+		arg.QuestionSetID,
+		arg.QuestionID,
+		arg.Operator,
+		arg.Value,
+		arg.TargetQuestionID,
+		arg.Position,
+	)
+	var i QuestionSetRule
+	err := row.Scan(
+		&i.ID,
+		&i.QuestionSetID,
+		&i.QuestionID,
+		&i.Operator,
+		&i.Value,
+		&i.TargetQuestionID,
+		&i.Position,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createResponse = `-- name: CreateResponse :one
+INSERT INTO responses (user_id, question_set_id, campaign_id)
+VALUES ($1, $2, $3)
+RETURNING id, user_id, question_set_id, campaign_id, status, started_at, completed_at, updated_at
+`
+
+type CreateResponseParams struct {
+	UserID        uuid.UUID     `json:"user_id"`
+	QuestionSetID uuid.UUID     `json:"question_set_id"`
+	CampaignID    uuid.NullUUID `json:"campaign_id"`
+}
+
+func (q *Queries) CreateResponse(ctx context.Context, arg CreateResponseParams) (Response, error) {
+	row := q.db.QueryRow(ctx, createResponse, arg.UserID, arg.QuestionSetID, arg.CampaignID)
+	var i Response
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.QuestionSetID,
+		&i.CampaignID,
+		&i.Status,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createWebhook = `-- name: CreateWebhook :one
+INSERT INTO webhooks (org_id, url, secret, event_types)
+VALUES ($1, $2, $3, $4)
+RETURNING id, org_id, url, secret, event_types, active, created_at, updated_at
+`
+
+type CreateWebhookParams struct {
+	OrgID      uuid.UUID `json:"org_id"`
+	Url        string    `json:"url"`
+	Secret     string    `json:"secret"`
+	EventTypes []string  `json:"event_types"`
+}
+
+func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error) {
+	row := q.db.QueryRow(ctx, createWebhook,
+		arg.OrgID,
+		arg.Url,
+		arg.Secret,
+		arg.EventTypes,
+	)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.OrgID,
+		&i.Url,
+		&i.Secret,
+		&i.EventTypes,
+		&i.Active,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteAnswersByUserID = `-- name: DeleteAnswersByUserID :execrows
+DELETE FROM answers
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteAnswersByUserID(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAnswersByUserID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteOutboxEventsByUserID = `-- name: DeleteOutboxEventsByUserID :execrows
+DELETE FROM outbox
+WHERE payload->>'user_id' = $1::TEXT
+`
+
+// Events about answers and responses carry the user in their payload.
+func (q *Queries) DeleteOutboxEventsByUserID(ctx context.Context, userID string) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteOutboxEventsByUserID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteQuestionByID = `-- name: DeleteQuestionByID :execrows
+DELETE FROM questions
+WHERE id = $1
+`
+
+func (q *Queries) DeleteQuestionByID(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteQuestionByID, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteQuestionSetQuestions = `-- name: DeleteQuestionSetQuestions :exec
+DELETE FROM question_set_questions
+WHERE question_set_id = $1
+`
+
+func (q *Queries) DeleteQuestionSetQuestions(ctx context.Context, questionSetID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteQuestionSetQuestions, questionSetID)
+	return err
+}
+
+const deleteQuestionSetRules = `-- name: DeleteQuestionSetRules :exec
+DELETE FROM question_set_rules
+WHERE question_set_id = $1
+`
+
+func (q *Queries) DeleteQuestionSetRules(ctx context.Context, questionSetID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteQuestionSetRules, questionSetID)
+	return err
+}
+
+const deleteResponsesByUserID = `-- name: DeleteResponsesByUserID :execrows
+DELETE FROM responses
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteResponsesByUserID(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteResponsesByUserID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteSentOutboxEvents = `-- name: DeleteSentOutboxEvents :execrows
+DELETE FROM outbox
+WHERE sent_at < $1
+`
+
+func (q *Queries) DeleteSentOutboxEvents(ctx context.Context, sentAt pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteSentOutboxEvents, sentAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteStaleQuestionSetRules = `-- name: DeleteStaleQuestionSetRules :execrows
+DELETE FROM question_set_rules
+WHERE question_set_rules.question_set_id = $1
+  AND (
+    NOT EXISTS (
+      SELECT 1 FROM question_set_questions
+      WHERE question_set_questions.question_set_id = question_set_rules.question_set_id
+        AND question_set_questions.question_id = question_set_rules.question_id
+    )
+    OR (
+      question_set_rules.target_question_id IS NOT NULL
+      AND NOT EXISTS (
+        SELECT 1 FROM question_set_questions
+        WHERE question_set_questions.question_set_id = question_set_rules.question_set_id
+          AND question_set_questions.question_id = question_set_rules.target_question_id
+      )
+    )
+  )
+`
+
+func (q *Queries) DeleteStaleQuestionSetRules(ctx context.Context, questionSetID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteStaleQuestionSetRules, questionSetID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteWebhookByID = `-- name: DeleteWebhookByID :execrows
+DELETE FROM webhooks
+WHERE id = $1
+`
+
+func (q *Queries) DeleteWebhookByID(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteWebhookByID, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteWebhookDeliveriesByUserID = `-- name: DeleteWebhookDeliveriesByUserID :execrows
+DELETE FROM webhook_deliveries
+WHERE payload->>'user_id' = $1::TEXT
+`
+
+func (q *Queries) DeleteWebhookDeliveriesByUserID(ctx context.Context, userID string) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteWebhookDeliveriesByUserID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const enqueueWebhookDeliveries = `-- name: EnqueueWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (webhook_id, event_type, payload)
+SELECT id, $1, $2
+FROM webhooks
+WHERE org_id = $3
+  AND active
+  AND $1::TEXT = ANY(event_types)
+`
+
+type EnqueueWebhookDeliveriesParams struct {
+	EventType string    `json:"event_type"`
+	Payload   []byte    `json:"payload"`
+	OrgID     uuid.UUID `json:"org_id"`
+}
+
+func (q *Queries) EnqueueWebhookDeliveries(ctx context.Context, arg EnqueueWebhookDeliveriesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, enqueueWebhookDeliveries, arg.EventType, arg.Payload, arg.OrgID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getAnswerByID = `-- name: GetAnswerByID :one
+SELECT id, selected_option, answer_text, user_id, question_id, question_set_id, created_at, updated_at, campaign_id, selected_options, response_id, idempotency_key, idempotency_fingerprint FROM answers
+WHERE id = $1
+`
+
+func (q *Queries) GetAnswerByID(ctx context.Context, id uuid.UUID) (Answer, error) {
+	row := q.db.QueryRow(ctx, getAnswerByID, id)
+	var i Answer
+	err := row.Scan(
+		&i.ID,
+		&i.SelectedOption,
+		&i.AnswerText,
+		&i.UserID,
+		&i.QuestionID,
+		&i.QuestionSetID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CampaignID,
+		&i.SelectedOptions,
+		&i.ResponseID,
+		&i.IdempotencyKey,
+		&i.IdempotencyFingerprint,
+	)
+	return i, err
+}
+
+const getAnswerByIDForUpdate = `-- name: GetAnswerByIDForUpdate :one
+SELECT id, selected_option, answer_text, user_id, question_id, question_set_id, created_at, updated_at, campaign_id, selected_options, response_id, idempotency_key, idempotency_fingerprint FROM answers
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetAnswerByIDForUpdate(ctx context.Context, id uuid.UUID) (Answer, error) {
+	row := q.db.QueryRow(ctx, getAnswerByIDForUpdate, id)
+	var i Answer
+	err := row.Scan(
+		&i.ID,
+		&i.SelectedOption,
+		&i.AnswerText,
+		&i.UserID,
+		&i.QuestionID,
+		&i.QuestionSetID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CampaignID,
+		&i.SelectedOptions,
+		&i.ResponseID,
+		&i.IdempotencyKey,
+		&i.IdempotencyFingerprint,
+	)
+	return i, err
+}
+
+const getAnswerByIdempotencyKey = `-- name: GetAnswerByIdempotencyKey :one
+SELECT id, selected_option, answer_text, user_id, question_id, question_set_id, created_at, updated_at, campaign_id, selected_options, response_id, idempotency_key, idempotency_fingerprint FROM answers
+WHERE user_id = $1 AND idempotency_key = $2
+`
+
+type GetAnswerByIdempotencyKeyParams struct {
+	UserID         uuid.UUID   `json:"user_id"`
+	IdempotencyKey pgtype.Text `json:"-"`
+}
+
+func (q *Queries) GetAnswerByIdempotencyKey(ctx context.Context, arg GetAnswerByIdempotencyKeyParams) (Answer, error) {
+	row := q.db.QueryRow(ctx, getAnswerByIdempotencyKey, arg.UserID, arg.IdempotencyKey)
+	var i Answer
+	err := row.Scan(
+		&i.ID,
+		&i.SelectedOption,
+		&i.AnswerText,
+		&i.UserID,
+		&i.QuestionID,
+		&i.QuestionSetID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CampaignID,
+		&i.SelectedOptions,
+		&i.ResponseID,
+		&i.IdempotencyKey,
+		&i.IdempotencyFingerprint,
+	)
+	return i, err
+}
+
+const getAnswers = `-- name: GetAnswers :many
+SELECT id, selected_option, answer_text, user_id, question_id, question_set_id, created_at, updated_at, campaign_id, selected_options, response_id, idempotency_key, idempotency_fingerprint
+FROM answers
+ORDER BY created_at DESC
+LIMIT $1 OFFSET $2
+`
+
+type GetAnswersParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+func (q *Queries) GetAnswers(ctx context.Context, arg GetAnswersParams) ([]Answer, error) {
+	rows, err := q.db.Query(ctx, getAnswers, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Answer
+	for rows.Next() {
+		var i Answer
+		if err := rows.Scan(
+			&i.ID,
+			&i.SelectedOption,
+			&i.AnswerText,
+			&i.UserID,
+			&i.QuestionID,
+			&i.QuestionSetID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CampaignID,
+			&i.SelectedOptions,
+			&i.ResponseID,
+			&i.IdempotencyKey,
+			&i.IdempotencyFingerprint,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAnswersByQuestionID = `-- name: GetAnswersByQuestionID :many
+SELECT id, selected_option, answer_text, user_id, question_id, question_set_id, created_at, updated_at, campaign_id, selected_options, response_id, idempotency_key, idempotency_fingerprint
+FROM answers 
+WHERE question_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type GetAnswersByQuestionIDParams struct {
+	QuestionID uuid.UUID `json:"question_id"`
+	Limit      int32     `json:"limit"`
+	Offset     int32     `json:"offset"`
+}
+
+func (q *Queries) GetAnswersByQuestionID(ctx context.Context, arg GetAnswersByQuestionIDParams) ([]Answer, error) {
+	rows, err := q.db.Query(ctx, getAnswersByQuestionID, arg.QuestionID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Answer
+	for rows.Next() {
+		var i Answer
+		if err := rows.Scan(
+			&i.ID,
+			&i.SelectedOption,
+			&i.AnswerText,
+			&i.UserID,
+			&i.QuestionID,
+			&i.QuestionSetID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CampaignID,
+			&i.SelectedOptions,
+			&i.ResponseID,
+			&i.IdempotencyKey,
+			&i.IdempotencyFingerprint,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAnswersByResponseID = `-- name: GetAnswersByResponseID :many
+SELECT id, selected_option, answer_text, user_id, question_id, question_set_id, created_at, updated_at, campaign_id, selected_options, response_id, idempotency_key, idempotency_fingerprint FROM answers
+WHERE response_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) GetAnswersByResponseID(ctx context.Context, responseID uuid.NullUUID) ([]Answer, error) {
+	rows, err := q.db.Query(ctx, getAnswersByResponseID, responseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Answer
+	for rows.Next() {
+		var i Answer
+		if err := rows.Scan(
+			&i.ID,
+			&i.SelectedOption,
+			&i.AnswerText,
+			&i.UserID,
+			&i.QuestionID,
+			&i.QuestionSetID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CampaignID,
+			&i.SelectedOptions,
+			&i.ResponseID,
+			&i.IdempotencyKey,
+			&i.IdempotencyFingerprint,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCampaignAnswerCounts = `-- name: GetCampaignAnswerCounts :many
+SELECT question_id, COUNT(*) AS answers
+FROM answers
+WHERE campaign_id = $1
+  AND ($2::UUID[] IS NULL OR question_id = ANY($2::UUID[]))
+GROUP BY question_id
+`
+
+type GetCampaignAnswerCountsParams struct {
+	CampaignID  uuid.NullUUID `json:"campaign_id"`
+	QuestionIds []uuid.UUID   `json:"question_ids"`
+}
+
+type GetCampaignAnswerCountsRow struct {
+	QuestionID uuid.UUID `json:"question_id"`
+	Answers    int64     `json:"answers"`
+}
+
+func (q *Queries) GetCampaignAnswerCounts(ctx context.Context, arg GetCampaignAnswerCountsParams) ([]GetCampaignAnswerCountsRow, error) {
+	rows, err := q.db.Query(ctx, getCampaignAnswerCounts, arg.CampaignID, arg.QuestionIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCampaignAnswerCountsRow
+	for rows.Next() {
+		var i GetCampaignAnswerCountsRow
+		if err := rows.Scan(&i.QuestionID, &i.Answers); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCampaignAnswerTotals = `-- name: GetCampaignAnswerTotals :one
+SELECT
+  COUNT(*) AS responses,
+  COUNT(DISTINCT user_id) AS respondents,
+  MIN(created_at)::TIMESTAMPTZ AS first_answered_at,
+  MAX(created_at)::TIMESTAMPTZ AS last_answered_at
+FROM answers
+WHERE campaign_id = $1
+`
+
+type GetCampaignAnswerTotalsRow struct {
+	Responses       int64              `json:"responses"`
+	Respondents     int64              `json:"respondents"`
+	FirstAnsweredAt pgtype.Timestamptz `json:"first_answered_at"`
+	LastAnsweredAt  pgtype.Timestamptz `json:"last_answered_at"`
+}
+
+func (q *Queries) GetCampaignAnswerTotals(ctx context.Context, campaignID uuid.NullUUID) (GetCampaignAnswerTotalsRow, error) {
+	row := q.db.QueryRow(ctx, getCampaignAnswerTotals, campaignID)
+	var i GetCampaignAnswerTotalsRow
+	err := row.Scan(
+		&i.Responses,
+		&i.Respondents,
+		&i.FirstAnsweredAt,
+		&i.LastAnsweredAt,
+	)
+	return i, err
+}
+
+const getCampaignByID = `-- name: GetCampaignByID :one
+SELECT id, org_id, name, status, starts_at, ends_at, created_at, updated_at, answer_policy FROM campaigns
+WHERE id = $1
+`
+
+func (q *Queries) GetCampaignByID(ctx context.Context, id uuid.UUID) (Campaign, error) {
+	row := q.db.QueryRow(ctx, getCampaignByID, id)
+	var i Campaign
+	err := row.Scan(
+		&i.ID,
+		&i.OrgID,
+		&i.Name,
+		&i.Status,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.AnswerPolicy,
+	)
+	return i, err
+}
+
+const getCampaignByIDForShare = `-- name: GetCampaignByIDForShare :one
+SELECT id, org_id, name, status, starts_at, ends_at, created_at, updated_at, answer_policy FROM campaigns
+WHERE id = $1
+FOR SHARE
+`
+
+func (q *Queries) GetCampaignByIDForShare(ctx context.Context, id uuid.UUID) (Campaign, error) {
+	row := q.db.QueryRow(ctx, getCampaignByIDForShare, id)
+	var i Campaign
+	err := row.Scan(
+		&i.ID,
+		&i.OrgID,
+		&i.Name,
+		&i.Status,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.AnswerPolicy,
+	)
+	return i, err
+}
+
+const getCampaignByIDForUpdate = `-- name: GetCampaignByIDForUpdate :one
+SELECT id, org_id, name, status, starts_at, ends_at, created_at, updated_at, answer_policy FROM campaigns
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetCampaignByIDForUpdate(ctx context.Context, id uuid.UUID) (Campaign, error) {
+	row := q.db.QueryRow(ctx, getCampaignByIDForUpdate, id)
+	var i Campaign
+	err := row.Scan(
+		&i.ID,
+		&i.OrgID,
+		&i.Name,
+		&i.Status,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.AnswerPolicy,
+	)
+	return i, err
+}
+
+const getCampaignOptionCounts = `-- name: GetCampaignOptionCounts :many
+SELECT answers.question_id, selected.option::TEXT AS option, COUNT(*) AS answers
+FROM answers, unnest(answers.selected_options) AS selected(option)
+WHERE answers.campaign_id = $1
+  AND ($2::UUID[] IS NULL OR answers.question_id = ANY($2::UUID[]))
+GROUP BY answers.question_id, selected.option
+ORDER BY answers.question_id, COUNT(*) DESC, selected.option
+`
+
+type GetCampaignOptionCountsParams struct {
+	CampaignID  uuid.NullUUID `json:"campaign_id"`
+	QuestionIds []uuid.UUID   `json:"question_ids"`
+}
+
+type GetCampaignOptionCountsRow struct {
+	QuestionID uuid.UUID `json:"question_id"`
+	Option     string    `json:"option"`
+	Answers    int64     `json:"answers"`
+}
+
+func (q *Queries) GetCampaignOptionCounts(ctx context.Context, arg GetCampaignOptionCountsParams) ([]GetCampaignOptionCountsRow, error) {
+	rows, err := q.db.Query(ctx, getCampaignOptionCounts, arg.CampaignID, arg.QuestionIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCampaignOptionCountsRow
+	for rows.Next() {
+		var i GetCampaignOptionCountsRow
+		if err := rows.Scan(&i.QuestionID, &i.Option, &i.Answers); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCampaignQuestionSummaries = `-- name: GetCampaignQuestionSummaries :many
+SELECT
+  questions.id AS question_id,
+  questions.question_type,
+  questions.options,
+  COUNT(answers.user_id) AS responses,
+  COUNT(DISTINCT answers.user_id) AS respondents,
+  MIN(answers.created_at)::TIMESTAMPTZ AS first_answered_at,
+  MAX(answers.created_at)::TIMESTAMPTZ AS last_answered_at
+FROM (
+  SELECT DISTINCT question_mappings.question_id
+  FROM question_mappings
+  WHERE question_mappings.campaign_id = $1
+) AS mapped
+JOIN questions ON questions.id = mapped.question_id
+LEFT JOIN answers ON answers.campaign_id = $1 AND answers.question_id = mapped.question_id
+GROUP BY questions.id
+ORDER BY questions.id
+`
+
+type GetCampaignQuestionSummariesRow struct {
+	QuestionID      uuid.UUID          `json:"question_id"`
+	QuestionType    QuestionType       `json:"question_type"`
+	Options         []string           `json:"options"`
+	Responses       int64              `json:"responses"`
+	Respondents     int64              `json:"respondents"`
+	FirstAnsweredAt pgtype.Timestamptz `json:"first_answered_at"`
+	LastAnsweredAt  pgtype.Timestamptz `json:"last_answered_at"`
+}
+
+// Summarises the answers to each question mapped to a campaign, including
+// questions that have not been answered yet.
+func (q *Queries) GetCampaignQuestionSummaries(ctx context.Context, campaignID uuid.UUID) ([]GetCampaignQuestionSummariesRow, error) {
+	rows, err := q.db.Query(ctx, getCampaignQuestionSummaries, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCampaignQuestionSummariesRow
+	for rows.Next() {
+		var i GetCampaignQuestionSummariesRow
+		if err := rows.Scan(
+			&i.QuestionID,
+			&i.QuestionType,
+			&i.Options,
+			&i.Responses,
+			&i.Respondents,
+			&i.FirstAnsweredAt,
+			&i.LastAnsweredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCampaignsByOrgID = `-- name: GetCampaignsByOrgID :many
+SELECT id, org_id, name, status, starts_at, ends_at, created_at, updated_at, answer_policy FROM campaigns
+WHERE org_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type GetCampaignsByOrgIDParams struct {
+	OrgID  uuid.UUID `json:"org_id"`
+	Limit  int32     `json:"limit"`
+	Offset int32     `json:"offset"`
+}
+
+func (q *Queries) GetCampaignsByOrgID(ctx context.Context, arg GetCampaignsByOrgIDParams) ([]Campaign, error) {
+	rows, err := q.db.Query(ctx, getCampaignsByOrgID, arg.OrgID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Campaign
+	for rows.Next() {
+		var i Campaign
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrgID,
+			&i.Name,
+			&i.Status,
+			&i.StartsAt,
+			&i.EndsAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.AnswerPolicy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLatestQuestionSetVersion = `-- name: GetLatestQuestionSetVersion :one
+SELECT MAX(version)::INTEGER FROM question_sets
+WHERE lineage_id = $1
+`
+
+func (q *Queries) GetLatestQuestionSetVersion(ctx context.Context, lineageID uuid.UUID) (int32, error) {
+	row := q.db.QueryRow(ctx, getLatestQuestionSetVersion, lineageID)
+	var column_1 int32
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const getLatestUserAnswer = `-- name: GetLatestUserAnswer :one
+SELECT id, selected_option, answer_text, user_id, question_id, question_set_id, created_at, updated_at, campaign_id, selected_options, response_id, idempotency_key, idempotency_fingerprint FROM answers
+WHERE user_id = $1
+  AND question_id = $2
+  AND question_set_id = $3
+  AND campaign_id IS NOT DISTINCT FROM $4
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+type GetLatestUserAnswerParams struct {
+	UserID        uuid.UUID     `json:"user_id"`
+	QuestionID    uuid.UUID     `json:"question_id"`
+	QuestionSetID uuid.UUID     `json:"question_set_id"`
+	CampaignID    uuid.NullUUID `json:"campaign_id"`
+}
+
+func (q *Queries) GetLatestUserAnswer(ctx context.Context, arg GetLatestUserAnswerParams) (Answer, error) {
+	row := q.db.QueryRow(ctx, getLatestUserAnswer,
+		arg.UserID,
+		arg.QuestionID,
+		arg.QuestionSetID,
+		arg.CampaignID,
+	)
+	var i Answer
+	err := row.Scan(
+		&i.ID,
+		&i.SelectedOption,
+		&i.AnswerText,
+		&i.UserID,
+		&i.QuestionID,
+		&i.QuestionSetID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CampaignID,
+		&i.SelectedOptions,
+		&i.ResponseID,
+		&i.IdempotencyKey,
+		&i.IdempotencyFingerprint,
+	)
+	return i, err
+}
+
+const getOpenResponse = `-- name: GetOpenResponse :one
+SELECT id, user_id, question_set_id, campaign_id, status, started_at, completed_at, updated_at FROM responses
+WHERE user_id = $1
+  AND question_set_id = $2
+  AND campaign_id IS NOT DISTINCT FROM $3
+  AND status = 'in_progress'
+`
+
+type GetOpenResponseParams struct {
+	UserID        uuid.UUID     `json:"user_id"`
+	QuestionSetID uuid.UUID     `json:"question_set_id"`
+	CampaignID    uuid.NullUUID `json:"campaign_id"`
+}
+
+func (q *Queries) GetOpenResponse(ctx context.Context, arg GetOpenResponseParams) (Response, error) {
+	row := q.db.QueryRow(ctx, getOpenResponse, arg.UserID, arg.QuestionSetID, arg.CampaignID)
+	var i Response
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.QuestionSetID,
+		&i.CampaignID,
+		&i.Status,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getQuestionByID = `-- name: GetQuestionByID :one
+SELECT id, question_type, prompt, options, created_at, updated_at, required, min_value, max_value, max_length FROM questions
+WHERE id = $1
+`
+
+func (q *Queries) GetQuestionByID(ctx context.Context, id uuid.UUID) (Question, error) {
+	row := q.db.QueryRow(ctx, getQuestionByID, id)
+	var i Question
+	err := row.Scan(
+		&i.ID,
+		&i.QuestionType,
+		&i.Prompt,
+		&i.Options,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Required,
+		&i.MinValue,
+		&i.MaxValue,
+		&i.MaxLength,
+	)
+	return i, err
+}
+
+const getQuestionMappingByIDForUpdate = `-- name: GetQuestionMappingByIDForUpdate :one
+SELECT id, question_id, campaign_id, org_id, created_at, updated_at FROM question_mappings
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetQuestionMappingByIDForUpdate(ctx context.Context, id uuid.UUID) (QuestionMapping, error) {
+	row := q.db.QueryRow(ctx, getQuestionMappingByIDForUpdate, id)
+	var i QuestionMapping
+	err := row.Scan(
+		&i.ID,
+		&i.QuestionID,
+		&i.CampaignID,
+		&i.OrgID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getQuestionMappingsByCampaignID = `-- name: GetQuestionMappingsByCampaignID :many
+SELECT id, question_id, campaign_id, org_id, created_at, updated_at FROM question_mappings 
+WHERE campaign_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type GetQuestionMappingsByCampaignIDParams struct {
+	CampaignID uuid.UUID `json:"campaign_id"`
+	Limit      int32     `json:"limit"`
+	Offset     int32     `json:"offset"`
+}
+
+func (q *Queries) GetQuestionMappingsByCampaignID(ctx context.Context, arg GetQuestionMappingsByCampaignIDParams) ([]QuestionMapping, error) {
+	rows, err := q.db.Query(ctx, getQuestionMappingsByCampaignID, arg.CampaignID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []QuestionMapping
+	for rows.Next() {
+		var i QuestionMapping
+		if err := rows.Scan(
+			&i.ID,
+			&i.QuestionID,
+			&i.CampaignID,
+			&i.OrgID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getQuestionOptionCounts = `-- name: GetQuestionOptionCounts :many
+SELECT selected.option::TEXT AS option, COUNT(*) AS answers
+FROM answers
+CROSS JOIN unnest(answers.selected_options) AS selected(option)
+LEFT JOIN campaigns ON campaigns.id = answers.campaign_id
+WHERE answers.question_id = $1
+  AND ($2::UUID IS NULL OR answers.campaign_id = $2)
+  AND ($3::UUID IS NULL OR campaigns.org_id = $3)
+  AND ($4::TIMESTAMPTZ IS NULL OR answers.created_at >= $4)
+  AND ($5::TIMESTAMPTZ IS NULL OR answers.created_at < $5)
+GROUP BY selected.option
+ORDER BY answers DESC, option
+`
+
+type GetQuestionOptionCountsParams struct {
+	QuestionID   uuid.UUID          `json:"question_id"`
+	CampaignID   uuid.NullUUID      `json:"campaign_id"`
+	OrgID        uuid.NullUUID      `json:"org_id"`
+	AnsweredFrom pgtype.Timestamptz `json:"answered_from"`
+	AnsweredTo   pgtype.Timestamptz `json:"answered_to"`
+}
+
+type GetQuestionOptionCountsRow struct {
+	Option  string `json:"option"`
+	Answers int64  `json:"answers"`
+}
+
+func (q *Queries) GetQuestionOptionCounts(ctx context.Context, arg GetQuestionOptionCountsParams) ([]GetQuestionOptionCountsRow, error) {
+	rows, err := q.db.Query(ctx, getQuestionOptionCounts,
+		arg.QuestionID,
+		arg.CampaignID,
+		arg.OrgID,
+		arg.AnsweredFrom,
+		arg.AnsweredTo,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetQuestionOptionCountsRow
+	for rows.Next() {
+		var i GetQuestionOptionCountsRow
+		if err := rows.Scan(&i.Option, &i.Answers); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getQuestionResponseCounts = `-- name: GetQuestionResponseCounts :one
+SELECT COUNT(*) AS responses, COUNT(DISTINCT answers.user_id) AS respondents
+FROM answers
+LEFT JOIN campaigns ON campaigns.id = answers.campaign_id
+WHERE answers.question_id = $1
+  AND ($2::UUID IS NULL OR answers.campaign_id = $2)
+  AND ($3::UUID IS NULL OR campaigns.org_id = $3)
+  AND ($4::TIMESTAMPTZ IS NULL OR answers.created_at >= $4)
+  AND ($5::TIMESTAMPTZ IS NULL OR answers.created_at < $5)
+`
+
+type GetQuestionResponseCountsParams struct {
+	QuestionID   uuid.UUID          `json:"question_id"`
+	CampaignID   uuid.NullUUID      `json:"campaign_id"`
+	OrgID        uuid.NullUUID      `json:"org_id"`
+	AnsweredFrom pgtype.Timestamptz `json:"answered_from"`
+	AnsweredTo   pgtype.Timestamptz `json:"answered_to"`
+}
+
+type GetQuestionResponseCountsRow struct {
+	Responses   int64 `json:"responses"`
+	Respondents int64 `json:"respondents"`
+}
+
+// Answers to a question belong to an org through their campaign.
+func (q *Queries) GetQuestionResponseCounts(ctx context.Context, arg GetQuestionResponseCountsParams) (GetQuestionResponseCountsRow, error) {
+	row := q.db.QueryRow(ctx, getQuestionResponseCounts,
+		arg.QuestionID,
+		arg.CampaignID,
+		arg.OrgID,
+		arg.AnsweredFrom,
+		arg.AnsweredTo,
+	)
+	var i GetQuestionResponseCountsRow
+	err := row.Scan(&i.Responses, &i.Respondents)
+	return i, err
+}
+
+const getQuestionSetByID = `-- name: GetQuestionSetByID :one
+SELECT id, lineage_id, version, title, description, status, published_at, created_at, updated_at, answer_policy FROM question_sets
+WHERE id = $1
+`
+
+func (q *Queries) GetQuestionSetByID(ctx context.Context, id uuid.UUID) (QuestionSet, error) {
+	row := q.db.QueryRow(ctx, getQuestionSetByID, id)
+	var i QuestionSet
+	err := row.Scan(
+		&i.ID,
+		&i.LineageID,
+		&i.Version,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.PublishedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.AnswerPolicy,
+	)
+	return i, err
+}
+
+const getQuestionSetByIDForUpdate = `-- name: GetQuestionSetByIDForUpdate :one
+SELECT id, lineage_id, version, title, description, status, published_at, created_at, updated_at, answer_policy FROM question_sets
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetQuestionSetByIDForUpdate(ctx context.Context, id uuid.UUID) (QuestionSet, error) {
+	row := q.db.QueryRow(ctx, getQuestionSetByIDForUpdate, id)
+	var i QuestionSet
+	err := row.Scan(
+		&i.ID,
+		&i.LineageID,
+		&i.Version,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.PublishedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.AnswerPolicy,
+	)
+	return i, err
+}
+
+const getQuestionSetQuestions = `-- name: GetQuestionSetQuestions :many
+SELECT questions.id, questions.question_type, questions.prompt, questions.options, questions.created_at, questions.updated_at, questions.required, questions.min_value, questions.max_value, questions.max_length FROM question_set_questions
+JOIN questions ON questions.id = question_set_questions.question_id
+WHERE question_set_questions.question_set_id = $1
+ORDER BY question_set_questions.position
+`
+
+func (q *Queries) GetQuestionSetQuestions(ctx context.Context, questionSetID uuid.UUID) ([]Question, error) {
+	rows, err := q.db.Query(ctx, getQuestionSetQuestions, questionSetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Question
+	for rows.Next() {
+		var i Question
+		if err := rows.Scan(
+			&i.ID,
+			&i.QuestionType,
+			&i.Prompt,
+			&i.Options,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Required,
+			&i.MinValue,
+			&i.MaxValue,
+			&i.MaxLength,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getQuestionSetRules = `-- name: GetQuestionSetRules :many
+SELECT id, question_set_id, question_id, operator, value, target_question_id, position, created_at FROM question_set_rules
+WHERE question_set_id = $1
+ORDER BY position
+`
+
+func (q *Queries) GetQuestionSetRules(ctx context.Context, questionSetID uuid.UUID) ([]QuestionSetRule, error) {
+	rows, err := q.db.Query(ctx, getQuestionSetRules, questionSetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []QuestionSetRule
+	for rows.Next() {
+		var i QuestionSetRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.QuestionSetID,
+			&i.QuestionID,
+			&i.Operator,
+			&i.Value,
+			&i.TargetQuestionID,
+			&i.Position,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getQuestionSetStatusesByQuestionID = `-- name: GetQuestionSetStatusesByQuestionID :many
+SELECT question_sets.status
+FROM question_sets
+JOIN question_set_questions ON question_set_questions.question_set_id = question_sets.id
+WHERE question_set_questions.question_id = $1
+FOR SHARE OF question_sets
+`
+
+// Locks the question sets a question belongs to against being published while
+// the question is being edited.
+func (q *Queries) GetQuestionSetStatusesByQuestionID(ctx context.Context, questionID uuid.UUID) ([]QuestionSetStatus, error) {
+	rows, err := q.db.Query(ctx, getQuestionSetStatusesByQuestionID, questionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []QuestionSetStatus
+	for rows.Next() {
+		var status QuestionSetStatus
+		if err := rows.Scan(&status); err != nil {
+			return nil, err
+		}
+		items = append(items, status)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getQuestionSets = `-- name: GetQuestionSets :many
+SELECT id, lineage_id, version, title, description, status, published_at, created_at, updated_at, answer_policy FROM question_sets
+ORDER BY created_at DESC
+LIMIT $1 OFFSET $2
+`
+
+type GetQuestionSetsParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+func (q *Queries) GetQuestionSets(ctx context.Context, arg GetQuestionSetsParams) ([]QuestionSet, error) {
+	rows, err := q.db.Query(ctx, getQuestionSets, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []QuestionSet
+	for rows.Next() {
+		var i QuestionSet
+		if err := rows.Scan(
+			&i.ID,
+			&i.LineageID,
+			&i.Version,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.PublishedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.AnswerPolicy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getQuestionSetsByLineageID = `-- name: GetQuestionSetsByLineageID :many
+SELECT id, lineage_id, version, title, description, status, published_at, created_at, updated_at, answer_policy FROM question_sets
+WHERE lineage_id = $1
+ORDER BY version DESC
+LIMIT $2 OFFSET $3
+`
+
+type GetQuestionSetsByLineageIDParams struct {
+	LineageID uuid.UUID `json:"lineage_id"`
+	Limit     int32     `json:"limit"`
+	Offset    int32     `json:"offset"`
+}
+
+func (q *Queries) GetQuestionSetsByLineageID(ctx context.Context, arg GetQuestionSetsByLineageIDParams) ([]QuestionSet, error) {
+	rows, err := q.db.Query(ctx, getQuestionSetsByLineageID, arg.LineageID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []QuestionSet
+	for rows.Next() {
+		var i QuestionSet
+		if err := rows.Scan(
+			&i.ID,
+			&i.LineageID,
+			&i.Version,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.PublishedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.AnswerPolicy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getQuestions = `-- name: GetQuestions :many
+SELECT id, question_type, prompt, options, created_at, updated_at, required, min_value, max_value, max_length FROM questions
 ORDER BY created_at DESC
 LIMIT $1 OFFSET $2
 `
 
-type GetAnswersParams struct {
+type GetQuestionsParams struct {
 	Limit  int32 `json:"limit"`
 	Offset int32 `json:"offset"`
 }
 
-func (q *Queries) GetAnswers(ctx context.Context, arg GetAnswersParams) ([]Answer, error) {
-	rows, err := q.db.Query(ctx, getAnswers, arg.Limit, arg.Offset)
+func (q *Queries) GetQuestions(ctx context.Context, arg GetQuestionsParams) ([]Question, error) {
+	rows, err := q.db.Query(ctx, getQuestions, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Answer
+	var items []Question
 	for rows.Next() {
-		var i Answer
+		var i Question
 		if err := rows.Scan(
 			&i.ID,
-			&i.SelectedOption,
-			&i.AnswerText,
-			&i.UserID,
-			&i.QuestionID,
-			&i.QuestionSetID,
+			&i.QuestionType,
+			&i.Prompt,
+			&i.Options,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Required,
+			&i.MinValue,
+			&i.MaxValue,
+			&i.MaxLength,
 		); err != nil {
 			return nil, err
 		}
@@ -123,38 +1721,127 @@ func (q *Queries) GetAnswers(ctx context.Context, arg GetAnswersParams) ([]Answe
 	return items, nil
 }
 
-const getAnswersByQuestionID = `-- name: GetAnswersByQuestionID :many
-SELECT id, selected_option, answer_text, user_id, question_id, question_set_id, created_at, updated_at
-FROM answers 
-WHERE question_id = $1
+const getResponseByID = `-- name: GetResponseByID :one
+SELECT id, user_id, question_set_id, campaign_id, status, started_at, completed_at, updated_at FROM responses
+WHERE id = $1
+`
+
+func (q *Queries) GetResponseByID(ctx context.Context, id uuid.UUID) (Response, error) {
+	row := q.db.QueryRow(ctx, getResponseByID, id)
+	var i Response
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.QuestionSetID,
+		&i.CampaignID,
+		&i.Status,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getResponseByIDForShare = `-- name: GetResponseByIDForShare :one
+SELECT id, user_id, question_set_id, campaign_id, status, started_at, completed_at, updated_at FROM responses
+WHERE id = $1
+FOR SHARE
+`
+
+func (q *Queries) GetResponseByIDForShare(ctx context.Context, id uuid.UUID) (Response, error) {
+	row := q.db.QueryRow(ctx, getResponseByIDForShare, id)
+	var i Response
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.QuestionSetID,
+		&i.CampaignID,
+		&i.Status,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getResponseByIDForUpdate = `-- name: GetResponseByIDForUpdate :one
+SELECT id, user_id, question_set_id, campaign_id, status, started_at, completed_at, updated_at FROM responses
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetResponseByIDForUpdate(ctx context.Context, id uuid.UUID) (Response, error) {
+	row := q.db.QueryRow(ctx, getResponseByIDForUpdate, id)
+	var i Response
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.QuestionSetID,
+		&i.CampaignID,
+		&i.Status,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getWebhookByID = `-- name: GetWebhookByID :one
+SELECT id, org_id, url, secret, event_types, active, created_at, updated_at FROM webhooks
+WHERE id = $1
+`
+
+func (q *Queries) GetWebhookByID(ctx context.Context, id uuid.UUID) (Webhook, error) {
+	row := q.db.QueryRow(ctx, getWebhookByID, id)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.OrgID,
+		&i.Url,
+		&i.Secret,
+		&i.EventTypes,
+		&i.Active,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getWebhookDeliveries = `-- name: GetWebhookDeliveries :many
+SELECT id, webhook_id, event_type, payload, status, attempts, response_status, last_error, next_attempt_at, created_at, delivered_at FROM webhook_deliveries
+WHERE webhook_id = $1
+  AND ($2::webhook_delivery_status IS NULL OR status = $2)
 ORDER BY created_at DESC
-LIMIT $2 OFFSET $3
+LIMIT $3
 `
 
-type GetAnswersByQuestionIDParams struct {
-	QuestionID uuid.UUID `json:"question_id"`
-	Limit      int32     `json:"limit"`
-	Offset     int32     `json:"offset"`
+type GetWebhookDeliveriesParams struct {
+	WebhookID     uuid.UUID                 `json:"webhook_id"`
+	Status        NullWebhookDeliveryStatus `json:"status"`
+	MaxDeliveries int32                     `json:"max_deliveries"`
 }
 
-func (q *Queries) GetAnswersByQuestionID(ctx context.Context, arg GetAnswersByQuestionIDParams) ([]Answer, error) {
-	rows, err := q.db.Query(ctx, getAnswersByQuestionID, arg.QuestionID, arg.Limit, arg.Offset)
+func (q *Queries) GetWebhookDeliveries(ctx context.Context, arg GetWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.Query(ctx, getWebhookDeliveries, arg.WebhookID, arg.Status, arg.MaxDeliveries)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Answer
+	var items []WebhookDelivery
 	for rows.Next() {
-		var i Answer
+		var i WebhookDelivery
 		if err := rows.Scan(
 			&i.ID,
-			&i.SelectedOption,
-			&i.AnswerText,
-			&i.UserID,
-			&i.QuestionID,
-			&i.QuestionSetID,
+			&i.WebhookID,
+			&i.EventType,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.ResponseStatus,
+			&i.LastError,
+			&i.NextAttemptAt,
 			&i.CreatedAt,
-			&i.UpdatedAt,
+			&i.DeliveredAt,
 		); err != nil {
 			return nil, err
 		}
@@ -166,33 +1853,28 @@ func (q *Queries) GetAnswersByQuestionID(ctx context.Context, arg GetAnswersByQu
 	return items, nil
 }
 
-const getQuestionMappingsByCampaignID = `-- name: GetQuestionMappingsByCampaignID :many
-SELECT id, question_id, campaign_id, org_id, created_at, updated_at FROM question_mappings 
-WHERE campaign_id = $1
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3
+const getWebhooksByOrgID = `-- name: GetWebhooksByOrgID :many
+SELECT id, org_id, url, secret, event_types, active, created_at, updated_at FROM webhooks
+WHERE org_id = $1
+ORDER BY created_at
 `
 
-type GetQuestionMappingsByCampaignIDParams struct {
-	CampaignID uuid.UUID `json:"campaign_id"`
-	Limit      int32     `json:"limit"`
-	Offset     int32     `json:"offset"`
-}
-
-func (q *Queries) GetQuestionMappingsByCampaignID(ctx context.Context, arg GetQuestionMappingsByCampaignIDParams) ([]QuestionMapping, error) {
-	rows, err := q.db.Query(ctx, getQuestionMappingsByCampaignID, arg.CampaignID, arg.Limit, arg.Offset)
+func (q *Queries) GetWebhooksByOrgID(ctx context.Context, orgID uuid.UUID) ([]Webhook, error) {
+	rows, err := q.db.Query(ctx, getWebhooksByOrgID, orgID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []QuestionMapping
+	var items []Webhook
 	for rows.Next() {
-		var i QuestionMapping
+		var i Webhook
 		if err := rows.Scan(
 			&i.ID,
-			&i.QuestionID,
-			&i.CampaignID,
 			&i.OrgID,
+			&i.Url,
+			&i.Secret,
+			&i.EventTypes,
+			&i.Active,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -206,6 +1888,303 @@ func (q *Queries) GetQuestionMappingsByCampaignID(ctx context.Context, arg GetQu
 	return items, nil
 }
 
+const isQuestionInSet = `-- name: IsQuestionInSet :one
+SELECT EXISTS (
+  SELECT 1 FROM question_set_questions
+  WHERE question_set_id = $1 AND question_id = $2
+)
+`
+
+type IsQuestionInSetParams struct {
+	QuestionSetID uuid.UUID `json:"question_set_id"`
+	QuestionID    uuid.UUID `json:"question_id"`
+}
+
+func (q *Queries) IsQuestionInSet(ctx context.Context, arg IsQuestionInSetParams) (bool, error) {
+	row := q.db.QueryRow(ctx, isQuestionInSet, arg.QuestionSetID, arg.QuestionID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const lockUserAnswers = `-- name: LockUserAnswers :exec
+SELECT pg_advisory_xact_lock(hashtextextended($1::TEXT, 0))
+`
+
+// Serialises writes of answers by a user to a question until the end of the
+// transaction.
+func (q *Queries) LockUserAnswers(ctx context.Context, lockKey string) error {
+	_, err := q.db.Exec(ctx, lockUserAnswers, lockKey)
+	return err
+}
+
+const markOutboxEventsSent = `-- name: MarkOutboxEventsSent :exec
+UPDATE outbox
+SET sent_at = NOW(), attempts = attempts + 1, last_error = NULL
+WHERE id = ANY($1::BIGINT[])
+`
+
+func (q *Queries) MarkOutboxEventsSent(ctx context.Context, ids []int64) error {
+	_, err := q.db.Exec(ctx, markOutboxEventsSent, ids)
+	return err
+}
+
+const markWebhookDeliverySucceeded = `-- name: MarkWebhookDeliverySucceeded :exec
+UPDATE webhook_deliveries
+SET status = 'succeeded', attempts = attempts + 1, response_status = $2, last_error = NULL, delivered_at = NOW()
+WHERE id = $1
+`
+
+type MarkWebhookDeliverySucceededParams struct {
+	ID             uuid.UUID   `json:"id"`
+	ResponseStatus pgtype.Int4 `json:"response_status"`
+}
+
+func (q *Queries) MarkWebhookDeliverySucceeded(ctx context.Context, arg MarkWebhookDeliverySucceededParams) error {
+	_, err := q.db.Exec(ctx, markWebhookDeliverySucceeded, arg.ID, arg.ResponseStatus)
+	return err
+}
+
+const notifyAnswerChange = `-- name: NotifyAnswerChange :exec
+SELECT pg_notify('answer_changes', $1::TEXT)
+`
+
+// Delivered to listeners once the transaction commits.
+func (q *Queries) NotifyAnswerChange(ctx context.Context, payload string) error {
+	_, err := q.db.Exec(ctx, notifyAnswerChange, payload)
+	return err
+}
+
+const openScheduledCampaigns = `-- name: OpenScheduledCampaigns :execrows
+UPDATE campaigns
+SET status = 'live', updated_at = NOW()
+WHERE status = 'scheduled' AND starts_at <= NOW()
+`
+
+func (q *Queries) OpenScheduledCampaigns(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, openScheduledCampaigns)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const publishQuestionSet = `-- name: PublishQuestionSet :one
+UPDATE question_sets
+SET status = 'published', published_at = NOW(), updated_at = NOW()
+WHERE id = $1 RETURNING id, lineage_id, version, title, description, status, published_at, created_at, updated_at, answer_policy
+`
+
+func (q *Queries) PublishQuestionSet(ctx context.Context, id uuid.UUID) (QuestionSet, error) {
+	row := q.db.QueryRow(ctx, publishQuestionSet, id)
+	var i QuestionSet
+	err := row.Scan(
+		&i.ID,
+		&i.LineageID,
+		&i.Version,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.PublishedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.AnswerPolicy,
+	)
+	return i, err
+}
+
+const recordOutboxFailure = `-- name: RecordOutboxFailure :exec
+UPDATE outbox
+SET attempts = attempts + 1, last_error = $1
+WHERE id = ANY($2::BIGINT[])
+`
+
+type RecordOutboxFailureParams struct {
+	LastError pgtype.Text `json:"last_error"`
+	Ids       []int64     `json:"ids"`
+}
+
+func (q *Queries) RecordOutboxFailure(ctx context.Context, arg RecordOutboxFailureParams) error {
+	_, err := q.db.Exec(ctx, recordOutboxFailure, arg.LastError, arg.Ids)
+	return err
+}
+
+const recordWebhookDeliveryFailure = `-- name: RecordWebhookDeliveryFailure :exec
+UPDATE webhook_deliveries
+SET status = $2, attempts = attempts + 1, response_status = $3, last_error = $4, next_attempt_at = $5
+WHERE id = $1
+`
+
+type RecordWebhookDeliveryFailureParams struct {
+	ID             uuid.UUID             `json:"id"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	ResponseStatus pgtype.Int4           `json:"response_status"`
+	LastError      pgtype.Text           `json:"last_error"`
+	NextAttemptAt  pgtype.Timestamptz    `json:"next_attempt_at"`
+}
+
+func (q *Queries) RecordWebhookDeliveryFailure(ctx context.Context, arg RecordWebhookDeliveryFailureParams) error {
+	_, err := q.db.Exec(ctx, recordWebhookDeliveryFailure,
+		arg.ID,
+		arg.Status,
+		arg.ResponseStatus,
+		arg.LastError,
+		arg.NextAttemptAt,
+	)
+	return err
+}
+
+const updateAnswerByID = `-- name: UpdateAnswerByID :one
+UPDATE answers
+SET selected_option = $2, selected_options = $3, answer_text = $4, response_id = $5, updated_at = NOW()
+WHERE id = $1 RETURNING id, selected_option, answer_text, user_id, question_id, question_set_id, created_at, updated_at, campaign_id, selected_options, response_id, idempotency_key, idempotency_fingerprint
+`
+
+type UpdateAnswerByIDParams struct {
+	ID              uuid.UUID     `json:"id"`
+	SelectedOption  pgtype.Text   `json:"selected_option"`
+	SelectedOptions []string      `json:"selected_options"`
+	AnswerText      pgtype.Text   `json:"answer_text"`
+	ResponseID      uuid.NullUUID `json:"response_id"`
+}
+
+func (q *Queries) UpdateAnswerByID(ctx context.Context, arg UpdateAnswerByIDParams) (Answer, error) {
+	row := q.db.QueryRow(ctx, updateAnswerByID,
+		arg.ID,
+		arg.SelectedOption,
+		arg.SelectedOptions,
+		arg.AnswerText,
+		arg.ResponseID,
+	)
+	var i Answer
+	err := row.Scan(
+		&i.ID,
+		&i.SelectedOption,
+		&i.AnswerText,
+		&i.UserID,
+		&i.QuestionID,
+		&i.QuestionSetID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CampaignID,
+		&i.SelectedOptions,
+		&i.ResponseID,
+		&i.IdempotencyKey,
+		&i.IdempotencyFingerprint,
+	)
+	return i, err
+}
+
+const updateCampaignByID = `-- name: UpdateCampaignByID :one
+UPDATE campaigns
+SET name = $2, starts_at = $3, ends_at = $4, answer_policy = $5, updated_at = NOW()
+WHERE id = $1 RETURNING id, org_id, name, status, starts_at, ends_at, created_at, updated_at, answer_policy
+`
+
+type UpdateCampaignByIDParams struct {
+	ID           uuid.UUID          `json:"id"`
+	Name         string             `json:"name"`
+	StartsAt     pgtype.Timestamptz `json:"starts_at"`
+	EndsAt       pgtype.Timestamptz `json:"ends_at"`
+	AnswerPolicy NullAnswerPolicy   `json:"answer_policy"`
+}
+
+func (q *Queries) UpdateCampaignByID(ctx context.Context, arg UpdateCampaignByIDParams) (Campaign, error) {
+	row := q.db.QueryRow(ctx, updateCampaignByID,
+		arg.ID,
+		arg.Name,
+		arg.StartsAt,
+		arg.EndsAt,
+		arg.AnswerPolicy,
+	)
+	var i Campaign
+	err := row.Scan(
+		&i.ID,
+		&i.OrgID,
+		&i.Name,
+		&i.Status,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.AnswerPolicy,
+	)
+	return i, err
+}
+
+const updateCampaignStatus = `-- name: UpdateCampaignStatus :one
+UPDATE campaigns
+SET status = $2, updated_at = NOW()
+WHERE id = $1 RETURNING id, org_id, name, status, starts_at, ends_at, created_at, updated_at, answer_policy
+`
+
+type UpdateCampaignStatusParams struct {
+	ID     uuid.UUID      `json:"id"`
+	Status CampaignStatus `json:"status"`
+}
+
+func (q *Queries) UpdateCampaignStatus(ctx context.Context, arg UpdateCampaignStatusParams) (Campaign, error) {
+	row := q.db.QueryRow(ctx, updateCampaignStatus, arg.ID, arg.Status)
+	var i Campaign
+	err := row.Scan(
+		&i.ID,
+		&i.OrgID,
+		&i.Name,
+		&i.Status,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.AnswerPolicy,
+	)
+	return i, err
+}
+
+const updateQuestionByID = `-- name: UpdateQuestionByID :one
+UPDATE questions
+SET question_type = $2, prompt = $3, options = $4, required = $5,
+  min_value = $6, max_value = $7, max_length = $8, updated_at = NOW()
+WHERE id = $1 RETURNING id, question_type, prompt, options, created_at, updated_at, required, min_value, max_value, max_length
+`
+
+type UpdateQuestionByIDParams struct {
+	ID           uuid.UUID     `json:"id"`
+	QuestionType QuestionType  `json:"question_type"`
+	Prompt       string        `json:"prompt"`
+	Options      []string      `json:"options"`
+	Required     bool          `json:"required"`
+	MinValue     pgtype.Float8 `json:"min_value"`
+	MaxValue     pgtype.Float8 `json:"max_value"`
+	MaxLength    pgtype.Int4   `json:"max_length"`
+}
+
+func (q *Queries) UpdateQuestionByID(ctx context.Context, arg UpdateQuestionByIDParams) (Question, error) {
+	row := q.db.QueryRow(ctx, updateQuestionByID,
+		arg.ID,
+		arg.QuestionType,
+		arg.Prompt,
+		arg.Options,
+		arg.Required,
+		arg.MinValue,
+		arg.MaxValue,
+		arg.MaxLength,
+	)
+	var i Question
+	err := row.Scan(
+		&i.ID,
+		&i.QuestionType,
+		&i.Prompt,
+		&i.Options,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Required,
+		&i.MinValue,
+		&i.MaxValue,
+		&i.MaxLength,
+	)
+	return i, err
+}
+
 const updateQuestionMappingsByID = `-- name: UpdateQuestionMappingsByID :one
 UPDATE question_mappings
 SET campaign_id = $2, question_id = $3, org_id = $4, updated_at = NOW()
@@ -237,3 +2216,103 @@ func (q *Queries) UpdateQuestionMappingsByID(ctx context.Context, arg UpdateQues
 	)
 	return i, err
 }
+
+const updateQuestionSetByID = `-- name: UpdateQuestionSetByID :one
+UPDATE question_sets
+SET title = $2, description = $3, answer_policy = $4, updated_at = NOW()
+WHERE id = $1 RETURNING id, lineage_id, version, title, description, status, published_at, created_at, updated_at, answer_policy
+`
+
+type UpdateQuestionSetByIDParams struct {
+	ID           uuid.UUID    `json:"id"`
+	Title        string       `json:"title"`
+	Description  pgtype.Text  `json:"description"`
+	AnswerPolicy AnswerPolicy `json:"answer_policy"`
+}
+
+func (q *Queries) UpdateQuestionSetByID(ctx context.Context, arg UpdateQuestionSetByIDParams) (QuestionSet, error) {
+	row := q.db.QueryRow(ctx, updateQuestionSetByID,
+		arg.ID,
+		arg.Title,
+		arg.Description,
+		arg.AnswerPolicy,
+	)
+	var i QuestionSet
+	err := row.Scan(
+		&i.ID,
+		&i.LineageID,
+		&i.Version,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.PublishedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.AnswerPolicy,
+	)
+	return i, err
+}
+
+const updateQuestionSetStatus = `-- name: UpdateQuestionSetStatus :one
+UPDATE question_sets
+SET status = $2, updated_at = NOW()
+WHERE id = $1 RETURNING id, lineage_id, version, title, description, status, published_at, created_at, updated_at, answer_policy
+`
+
+type UpdateQuestionSetStatusParams struct {
+	ID     uuid.UUID         `json:"id"`
+	Status QuestionSetStatus `json:"status"`
+}
+
+func (q *Queries) UpdateQuestionSetStatus(ctx context.Context, arg UpdateQuestionSetStatusParams) (QuestionSet, error) {
+	row := q.db.QueryRow(ctx, updateQuestionSetStatus, arg.ID, arg.Status)
+	var i QuestionSet
+	err := row.Scan(
+		&i.ID,
+		&i.LineageID,
+		&i.Version,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.PublishedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.AnswerPolicy,
+	)
+	return i, err
+}
+
+const updateWebhookByID = `-- name: UpdateWebhookByID :one
+UPDATE webhooks
+SET url = $2, event_types = $3, active = $4, updated_at = NOW()
+WHERE id = $1
+RETURNING id, org_id, url, secret, event_types, active, created_at, updated_at
+`
+
+type UpdateWebhookByIDParams struct {
+	ID         uuid.UUID `json:"id"`
+	Url        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+}
+
+func (q *Queries) UpdateWebhookByID(ctx context.Context, arg UpdateWebhookByIDParams) (Webhook, error) {
+	row := q.db.QueryRow(ctx, updateWebhookByID,
+		arg.ID,
+		arg.Url,
+		arg.EventTypes,
+		arg.Active,
+	)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.OrgID,
+		&i.Url,
+		&i.Secret,
+		&i.EventTypes,
+		&i.Active,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
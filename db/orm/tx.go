@@ -0,0 +1,13 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Conn is a DBTX that can also open transactions, as *pgxpool.Pool does.
+type Conn interface {
+	DBTX
+	Begin(context.Context) (pgx.Tx, error)
+}
@@ -5,12 +5,69 @@ INSERT INTO
     answer_text,
     user_id,
     question_id,
-    question_set_id
+    question_set_id,
+    campaign_id,
+    selected_options,
+    response_id,
+    idempotency_key,
+    idempotency_fingerprint
   )
 VALUES
-  ($1, $2, $3, $4, $5)
+  ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+  ON CONFLICT (user_id, idempotency_key) DO NOTHING
   RETURNING *;
 
+-- name: CreateAnswerBatch :batchone
+INSERT INTO
+  answers (
+    selected_option,
+    answer_text,
+    user_id,
+    question_id,
+    question_set_id,
+    campaign_id,
+    selected_options,
+    response_id,
+    idempotency_key,
+    idempotency_fingerprint
+  )
+VALUES
+  ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+  ON CONFLICT (user_id, idempotency_key) DO NOTHING
+  RETURNING *;
+
+-- name: GetAnswerByIdempotencyKey :one
+SELECT * FROM answers
+WHERE user_id = $1 AND idempotency_key = $2;
+
+-- name: GetAnswerByID :one
+SELECT * FROM answers
+WHERE id = $1;
+
+-- name: GetAnswerByIDForUpdate :one
+SELECT * FROM answers
+WHERE id = $1
+FOR UPDATE;
+
+-- name: GetLatestUserAnswer :one
+SELECT * FROM answers
+WHERE user_id = $1
+  AND question_id = $2
+  AND question_set_id = $3
+  AND campaign_id IS NOT DISTINCT FROM $4
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: UpdateAnswerByID :one
+UPDATE answers
+SET selected_option = $2, selected_options = $3, answer_text = $4, response_id = $5, updated_at = NOW()
+WHERE id = $1 RETURNING *;
+
+-- name: LockUserAnswers :exec
+-- Serialises writes of answers by a user to a question until the end of the
+-- transaction.
+SELECT pg_advisory_xact_lock(hashtextextended(sqlc.arg(lock_key)::TEXT, 0));
+
 -- name: CreateQuestionMapping :one
 INSERT INTO question_mappings (question_id, campaign_id, org_id)
 VALUES ($1, $2, $3)
@@ -18,14 +75,14 @@ RETURNING *;
 
 
 -- name: GetAnswersByQuestionID :many
-SELECT id, selected_option, answer_text, user_id, question_id, question_set_id, created_at, updated_at
+SELECT *
 FROM answers 
 WHERE question_id = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3;
 
 -- name: GetAnswers :many
-SELECT id, selected_option, answer_text, user_id, question_id, question_set_id, created_at, updated_at
+SELECT *
 FROM answers
 ORDER BY created_at DESC
 LIMIT $1 OFFSET $2;
@@ -36,8 +93,424 @@ WHERE campaign_id = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3;
 
+-- name: GetQuestionMappingByIDForUpdate :one
+SELECT * FROM question_mappings
+WHERE id = $1
+FOR UPDATE;
+
 -- name: UpdateQuestionMappingsByID :one
 UPDATE question_mappings
 SET campaign_id = $2, question_id = $3, org_id = $4, updated_at = NOW()
 WHERE id = $1 RETURNING *;
 
+
+-- name: CreateQuestion :one
+INSERT INTO questions (question_type, prompt, options, required, min_value, max_value, max_length)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetQuestionByID :one
+SELECT * FROM questions
+WHERE id = $1;
+
+-- name: GetQuestions :many
+SELECT * FROM questions
+ORDER BY created_at DESC
+LIMIT $1 OFFSET $2;
+
+-- name: UpdateQuestionByID :one
+UPDATE questions
+SET question_type = $2, prompt = $3, options = $4, required = $5,
+  min_value = $6, max_value = $7, max_length = $8, updated_at = NOW()
+WHERE id = $1 RETURNING *;
+
+-- name: GetQuestionSetStatusesByQuestionID :many
+-- Locks the question sets a question belongs to against being published while
+-- the question is being edited.
+SELECT question_sets.status
+FROM question_sets
+JOIN question_set_questions ON question_set_questions.question_set_id = question_sets.id
+WHERE question_set_questions.question_id = $1
+FOR SHARE OF question_sets;
+
+-- name: DeleteQuestionByID :execrows
+DELETE FROM questions
+WHERE id = $1;
+
+-- name: CreateQuestionSet :one
+INSERT INTO question_sets (lineage_id, version, title, description, answer_policy)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetQuestionSetByID :one
+SELECT * FROM question_sets
+WHERE id = $1;
+
+-- name: GetQuestionSetByIDForUpdate :one
+SELECT * FROM question_sets
+WHERE id = $1
+FOR UPDATE;
+
+-- name: GetQuestionSets :many
+SELECT * FROM question_sets
+ORDER BY created_at DESC
+LIMIT $1 OFFSET $2;
+
+-- name: GetQuestionSetsByLineageID :many
+SELECT * FROM question_sets
+WHERE lineage_id = $1
+ORDER BY version DESC
+LIMIT $2 OFFSET $3;
+
+-- name: GetLatestQuestionSetVersion :one
+SELECT MAX(version)::INTEGER FROM question_sets
+WHERE lineage_id = $1;
+
+-- name: UpdateQuestionSetByID :one
+UPDATE question_sets
+SET title = $2, description = $3, answer_policy = $4, updated_at = NOW()
+WHERE id = $1 RETURNING *;
+
+-- name: UpdateQuestionSetStatus :one
+UPDATE question_sets
+SET status = $2, updated_at = NOW()
+WHERE id = $1 RETURNING *;
+
+-- name: PublishQuestionSet :one
+UPDATE question_sets
+SET status = 'published', published_at = NOW(), updated_at = NOW()
+WHERE id = $1 RETURNING *;
+
+-- name: AddQuestionSetQuestion :exec
+INSERT INTO question_set_questions (question_set_id, question_id, position)
+VALUES ($1, $2, $3);
+
+-- name: DeleteQuestionSetQuestions :exec
+DELETE FROM question_set_questions
+WHERE question_set_id = $1;
+
+-- name: GetQuestionSetQuestions :many
+SELECT questions.* FROM question_set_questions
+JOIN questions ON questions.id = question_set_questions.question_id
+WHERE question_set_questions.question_set_id = $1
+ORDER BY question_set_questions.position;
+
+-- name: IsQuestionInSet :one
+SELECT EXISTS (
+  SELECT 1 FROM question_set_questions
+  WHERE question_set_id = $1 AND question_id = $2
+);
+
+-- name: CreateCampaign :one
+INSERT INTO campaigns (org_id, name, status, starts_at, ends_at, answer_policy)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetCampaignByID :one
+SELECT * FROM campaigns
+WHERE id = $1;
+
+-- name: GetCampaignByIDForUpdate :one
+SELECT * FROM campaigns
+WHERE id = $1
+FOR UPDATE;
+
+-- name: GetCampaignByIDForShare :one
+SELECT * FROM campaigns
+WHERE id = $1
+FOR SHARE;
+
+-- name: GetCampaignsByOrgID :many
+SELECT * FROM campaigns
+WHERE org_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: UpdateCampaignByID :one
+UPDATE campaigns
+SET name = $2, starts_at = $3, ends_at = $4, answer_policy = $5, updated_at = NOW()
+WHERE id = $1 RETURNING *;
+
+-- name: UpdateCampaignStatus :one
+UPDATE campaigns
+SET status = $2, updated_at = NOW()
+WHERE id = $1 RETURNING *;
+
+-- name: OpenScheduledCampaigns :execrows
+UPDATE campaigns
+SET status = 'live', updated_at = NOW()
+WHERE status = 'scheduled' AND starts_at <= NOW();
+
+-- name: CloseEndedCampaigns :execrows
+UPDATE campaigns
+SET status = 'closed', updated_at = NOW()
+WHERE status IN ('scheduled', 'live', 'paused') AND ends_at <= NOW();
+
+-- name: CreateResponse :one
+INSERT INTO responses (user_id, question_set_id, campaign_id)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetResponseByID :one
+SELECT * FROM responses
+WHERE id = $1;
+
+-- name: GetResponseByIDForUpdate :one
+SELECT * FROM responses
+WHERE id = $1
+FOR UPDATE;
+
+-- name: GetResponseByIDForShare :one
+SELECT * FROM responses
+WHERE id = $1
+FOR SHARE;
+
+-- name: GetOpenResponse :one
+SELECT * FROM responses
+WHERE user_id = $1
+  AND question_set_id = $2
+  AND campaign_id IS NOT DISTINCT FROM $3
+  AND status = 'in_progress';
+
+-- name: CompleteResponse :one
+UPDATE responses
+SET status = 'completed', completed_at = NOW(), updated_at = NOW()
+WHERE id = $1 RETURNING *;
+
+-- name: AbandonResponse :one
+UPDATE responses
+SET status = 'abandoned', updated_at = NOW()
+WHERE id = $1 RETURNING *;
+
+-- name: GetAnswersByResponseID :many
+SELECT * FROM answers
+WHERE response_id = $1
+ORDER BY created_at;
+
+-- name: DeleteAnswersByUserID :execrows
+DELETE FROM answers
+WHERE user_id = $1;
+
+-- name: DeleteResponsesByUserID :execrows
+DELETE FROM responses
+WHERE user_id = $1;
+
+-- name: DeleteOutboxEventsByUserID :execrows
+-- Events about answers and responses carry the user in their payload.
+DELETE FROM outbox
+WHERE payload->>'user_id' = sqlc.arg(user_id)::TEXT;
+
+-- name: DeleteWebhookDeliveriesByUserID :execrows
+DELETE FROM webhook_deliveries
+WHERE payload->>'user_id' = sqlc.arg(user_id)::TEXT;
+
+-- name: CreateQuestionSetRule :one
+INSERT INTO question_set_rules (question_set_id, question_id, operator, value, target_question_id, position)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetQuestionSetRules :many
+SELECT * FROM question_set_rules
+WHERE question_set_id = $1
+ORDER BY position;
+
+-- name: DeleteQuestionSetRules :exec
+DELETE FROM question_set_rules
+WHERE question_set_id = $1;
+
+-- name: DeleteStaleQuestionSetRules :execrows
+DELETE FROM question_set_rules
+WHERE question_set_rules.question_set_id = $1
+  AND (
+    NOT EXISTS (
+      SELECT 1 FROM question_set_questions
+      WHERE question_set_questions.question_set_id = question_set_rules.question_set_id
+        AND question_set_questions.question_id = question_set_rules.question_id
+    )
+    OR (
+      question_set_rules.target_question_id IS NOT NULL
+      AND NOT EXISTS (
+        SELECT 1 FROM question_set_questions
+        WHERE question_set_questions.question_set_id = question_set_rules.question_set_id
+          AND question_set_questions.question_id = question_set_rules.target_question_id
+      )
+    )
+  );
+
+-- name: CreateOutboxEvent :exec
+INSERT INTO outbox (aggregate_type, aggregate_id, event_type, payload, headers)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: CreateOutboxEventBatch :batchexec
+INSERT INTO outbox (aggregate_type, aggregate_id, event_type, payload, headers)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: ClaimOutboxEvents :many
+SELECT * FROM outbox
+WHERE sent_at IS NULL
+  AND event_type = ANY(sqlc.arg(event_types)::TEXT[])
+  AND attempts < sqlc.arg(max_attempts)
+ORDER BY id
+LIMIT sqlc.arg(max_events)
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkOutboxEventsSent :exec
+UPDATE outbox
+SET sent_at = NOW(), attempts = attempts + 1, last_error = NULL
+WHERE id = ANY(sqlc.arg(ids)::BIGINT[]);
+
+-- name: RecordOutboxFailure :exec
+UPDATE outbox
+SET attempts = attempts + 1, last_error = sqlc.arg(last_error)
+WHERE id = ANY(sqlc.arg(ids)::BIGINT[]);
+
+-- name: DeleteSentOutboxEvents :execrows
+DELETE FROM outbox
+WHERE sent_at < $1;
+
+-- name: CreateWebhook :one
+INSERT INTO webhooks (org_id, url, secret, event_types)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetWebhookByID :one
+SELECT * FROM webhooks
+WHERE id = $1;
+
+-- name: GetWebhooksByOrgID :many
+SELECT * FROM webhooks
+WHERE org_id = $1
+ORDER BY created_at;
+
+-- name: UpdateWebhookByID :one
+UPDATE webhooks
+SET url = $2, event_types = $3, active = $4, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteWebhookByID :execrows
+DELETE FROM webhooks
+WHERE id = $1;
+
+-- name: EnqueueWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (webhook_id, event_type, payload)
+SELECT id, sqlc.arg(event_type), sqlc.arg(payload)
+FROM webhooks
+WHERE org_id = sqlc.arg(org_id)
+  AND active
+  AND sqlc.arg(event_type)::TEXT = ANY(event_types);
+
+-- name: EnqueueWebhookDeliveriesBatch :batchexec
+INSERT INTO webhook_deliveries (webhook_id, event_type, payload)
+SELECT id, sqlc.arg(event_type), sqlc.arg(payload)
+FROM webhooks
+WHERE org_id = sqlc.arg(org_id)
+  AND active
+  AND sqlc.arg(event_type)::TEXT = ANY(event_types);
+
+-- name: ClaimWebhookDeliveries :many
+WITH due AS (
+    SELECT webhook_deliveries.id FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT sqlc.arg(max_deliveries)
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE webhook_deliveries
+SET next_attempt_at = NOW() + make_interval(secs => sqlc.arg(lease_seconds)::FLOAT8)
+FROM due, webhooks
+WHERE webhook_deliveries.id = due.id AND webhooks.id = webhook_deliveries.webhook_id
+RETURNING webhook_deliveries.id, webhook_deliveries.event_type, webhook_deliveries.payload, webhook_deliveries.attempts, webhook_deliveries.created_at, webhooks.url, webhooks.secret;
+
+-- name: MarkWebhookDeliverySucceeded :exec
+UPDATE webhook_deliveries
+SET status = 'succeeded', attempts = attempts + 1, response_status = $2, last_error = NULL, delivered_at = NOW()
+WHERE id = $1;
+
+-- name: RecordWebhookDeliveryFailure :exec
+UPDATE webhook_deliveries
+SET status = $2, attempts = attempts + 1, response_status = $3, last_error = $4, next_attempt_at = $5
+WHERE id = $1;
+
+-- name: GetWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE webhook_id = sqlc.arg(webhook_id)
+  AND (sqlc.narg(status)::webhook_delivery_status IS NULL OR status = sqlc.narg(status))
+ORDER BY created_at DESC
+LIMIT sqlc.arg(max_deliveries);
+
+-- name: NotifyAnswerChange :exec
+-- Delivered to listeners once the transaction commits.
+SELECT pg_notify('answer_changes', sqlc.arg(payload)::TEXT);
+
+-- name: NotifyAnswerChangeBatch :batchexec
+SELECT pg_notify('answer_changes', sqlc.arg(payload)::TEXT);
+
+-- name: GetCampaignAnswerCounts :many
+SELECT question_id, COUNT(*) AS answers
+FROM answers
+WHERE campaign_id = sqlc.arg(campaign_id)
+  AND (sqlc.narg(question_ids)::UUID[] IS NULL OR question_id = ANY(sqlc.narg(question_ids)::UUID[]))
+GROUP BY question_id;
+
+-- name: GetCampaignOptionCounts :many
+SELECT answers.question_id, selected.option::TEXT AS option, COUNT(*) AS answers
+FROM answers, unnest(answers.selected_options) AS selected(option)
+WHERE answers.campaign_id = sqlc.arg(campaign_id)
+  AND (sqlc.narg(question_ids)::UUID[] IS NULL OR answers.question_id = ANY(sqlc.narg(question_ids)::UUID[]))
+GROUP BY answers.question_id, selected.option
+ORDER BY answers.question_id, COUNT(*) DESC, selected.option;
+
+-- name: GetQuestionResponseCounts :one
+-- Answers to a question belong to an org through their campaign.
+SELECT COUNT(*) AS responses, COUNT(DISTINCT answers.user_id) AS respondents
+FROM answers
+LEFT JOIN campaigns ON campaigns.id = answers.campaign_id
+WHERE answers.question_id = sqlc.arg(question_id)
+  AND (sqlc.narg(campaign_id)::UUID IS NULL OR answers.campaign_id = sqlc.narg(campaign_id))
+  AND (sqlc.narg(org_id)::UUID IS NULL OR campaigns.org_id = sqlc.narg(org_id))
+  AND (sqlc.narg(answered_from)::TIMESTAMPTZ IS NULL OR answers.created_at >= sqlc.narg(answered_from))
+  AND (sqlc.narg(answered_to)::TIMESTAMPTZ IS NULL OR answers.created_at < sqlc.narg(answered_to));
+
+-- name: GetQuestionOptionCounts :many
+SELECT selected.option::TEXT AS option, COUNT(*) AS answers
+FROM answers
+CROSS JOIN unnest(answers.selected_options) AS selected(option)
+LEFT JOIN campaigns ON campaigns.id = answers.campaign_id
+WHERE answers.question_id = sqlc.arg(question_id)
+  AND (sqlc.narg(campaign_id)::UUID IS NULL OR answers.campaign_id = sqlc.narg(campaign_id))
+  AND (sqlc.narg(org_id)::UUID IS NULL OR campaigns.org_id = sqlc.narg(org_id))
+  AND (sqlc.narg(answered_from)::TIMESTAMPTZ IS NULL OR answers.created_at >= sqlc.narg(answered_from))
+  AND (sqlc.narg(answered_to)::TIMESTAMPTZ IS NULL OR answers.created_at < sqlc.narg(answered_to))
+GROUP BY selected.option
+ORDER BY answers DESC, option;
+
+-- name: GetCampaignQuestionSummaries :many
+-- Summarises the answers to each question mapped to a campaign, including
+-- questions that have not been answered yet.
+SELECT
+  questions.id AS question_id,
+  questions.question_type,
+  questions.options,
+  COUNT(answers.user_id) AS responses,
+  COUNT(DISTINCT answers.user_id) AS respondents,
+  MIN(answers.created_at)::TIMESTAMPTZ AS first_answered_at,
+  MAX(answers.created_at)::TIMESTAMPTZ AS last_answered_at
+FROM (
+  SELECT DISTINCT question_mappings.question_id
+  FROM question_mappings
+  WHERE question_mappings.campaign_id = sqlc.arg(campaign_id)
+) AS mapped
+JOIN questions ON questions.id = mapped.question_id
+LEFT JOIN answers ON answers.campaign_id = sqlc.arg(campaign_id) AND answers.question_id = mapped.question_id
+GROUP BY questions.id
+ORDER BY questions.id;
+
+-- name: GetCampaignAnswerTotals :one
+SELECT
+  COUNT(*) AS responses,
+  COUNT(DISTINCT user_id) AS respondents,
+  MIN(created_at)::TIMESTAMPTZ AS first_answered_at,
+  MAX(created_at)::TIMESTAMPTZ AS last_answered_at
+FROM answers
+WHERE campaign_id = sqlc.arg(campaign_id);
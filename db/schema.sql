@@ -19,4 +19,193 @@ CREATE TABLE question_mappings (
     org_id UUID NOT NULL,
     created_at TIMESTAMPTZ DEFAULT NOW(),
     updated_at TIMESTAMPTZ DEFAULT NOW()
-);
\ No newline at end of file
+);
+
+CREATE TYPE question_type AS ENUM (
+    'single_choice',
+    'multi_choice',
+    'free_text',
+    'rating',
+    'numeric',
+    'date'
+);
+
+-- Create questions table
+CREATE TABLE questions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    question_type question_type NOT NULL,
+    prompt TEXT NOT NULL,
+    options TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+
+CREATE TYPE question_set_status AS ENUM (
+    'draft',
+    'published',
+    'archived'
+);
+
+-- Create question_sets table
+CREATE TABLE question_sets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    lineage_id UUID NOT NULL,
+    version INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    status question_set_status NOT NULL DEFAULT 'draft',
+    published_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (lineage_id, version)
+);
+
+-- Create question_set_questions table
+CREATE TABLE question_set_questions (
+    question_set_id UUID NOT NULL REFERENCES question_sets(id) ON DELETE CASCADE,
+    question_id UUID NOT NULL REFERENCES questions(id),
+    position INTEGER NOT NULL,
+    PRIMARY KEY (question_set_id, question_id),
+    UNIQUE (question_set_id, position)
+);
+
+
+CREATE TYPE campaign_status AS ENUM (
+    'draft',
+    'scheduled',
+    'live',
+    'paused',
+    'closed'
+);
+
+-- Create campaigns table
+CREATE TABLE campaigns (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    status campaign_status NOT NULL DEFAULT 'draft',
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    CHECK (starts_at IS NULL OR ends_at IS NULL OR ends_at > starts_at)
+);
+
+ALTER TABLE answers ADD COLUMN campaign_id UUID;
+
+ALTER TABLE questions
+    ADD COLUMN required BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN min_value DOUBLE PRECISION,
+    ADD COLUMN max_value DOUBLE PRECISION,
+    ADD COLUMN max_length INTEGER,
+    ADD CONSTRAINT questions_value_range CHECK (min_value IS NULL OR max_value IS NULL OR min_value <= max_value),
+    ADD CONSTRAINT questions_max_length CHECK (max_length IS NULL OR max_length > 0);
+
+ALTER TABLE answers ADD COLUMN selected_options TEXT[] NOT NULL DEFAULT '{}';
+
+
+CREATE TYPE response_status AS ENUM (
+    'in_progress',
+    'completed',
+    'abandoned'
+);
+
+-- Create responses table
+CREATE TABLE responses (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    question_set_id UUID NOT NULL REFERENCES question_sets(id),
+    campaign_id UUID REFERENCES campaigns(id),
+    status response_status NOT NULL DEFAULT 'in_progress',
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+ALTER TABLE answers ADD COLUMN response_id UUID REFERENCES responses(id);
+
+
+CREATE TYPE rule_operator AS ENUM (
+    'equals',
+    'not_equals',
+    'includes',
+    'greater_than',
+    'less_than'
+);
+
+-- Create question_set_rules table
+CREATE TABLE question_set_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    question_set_id UUID NOT NULL REFERENCES question_sets(id) ON DELETE CASCADE,
+    question_id UUID NOT NULL REFERENCES questions(id),
+    operator rule_operator NOT NULL,
+    value TEXT NOT NULL,
+    target_question_id UUID REFERENCES questions(id),
+    position INTEGER NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (question_set_id, position)
+);
+
+ALTER TABLE answers ADD COLUMN idempotency_key TEXT;
+ALTER TABLE answers ADD COLUMN idempotency_fingerprint TEXT;
+
+CREATE UNIQUE INDEX idx_answers_user_idempotency_key ON answers(user_id, idempotency_key);
+
+
+CREATE TYPE answer_policy AS ENUM (
+    'allow_multiple',
+    'keep_latest',
+    'reject_duplicates'
+);
+
+ALTER TABLE question_sets ADD COLUMN answer_policy answer_policy NOT NULL DEFAULT 'allow_multiple';
+ALTER TABLE campaigns ADD COLUMN answer_policy answer_policy;
+
+-- Create outbox table
+CREATE TABLE outbox (
+    id BIGSERIAL PRIMARY KEY,
+    aggregate_type TEXT NOT NULL,
+    aggregate_id UUID NOT NULL,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    headers JSONB NOT NULL DEFAULT '{}',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMPTZ
+);
+
+
+CREATE TYPE webhook_delivery_status AS ENUM (
+    'pending',
+    'succeeded',
+    'failed'
+);
+
+-- Create webhooks table
+CREATE TABLE webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID NOT NULL,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create webhook_deliveries table
+CREATE TABLE webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status webhook_delivery_status NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMPTZ
+);
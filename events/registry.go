@@ -0,0 +1,267 @@
+// Package events holds the versioned schemas of the events the service
+// consumes and decodes message payloads against them.
+//
+// A payload's encoding and schema version come from its content-type header,
+// for example
+//
+//	application/x-protobuf; version=1
+//	application/json; schema=answer_submitted; version=1
+//
+// Payloads without the header are taken to be JSON in version 1, which is
+// what producers sent before schemas were versioned.
+package events
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"path"
+	"regexp"
+	"strconv"
+
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	ContentTypeHeader   = "content-type"
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+
+	AnswerSubmittedSubject = "answer_submitted"
+
+	// LegacySchemaVersion is assumed for payloads that do not name a version.
+	LegacySchemaVersion = 1
+)
+
+var (
+	ErrUnknownSchema  = errors.New("unknown payload schema")
+	ErrInvalidPayload = errors.New("invalid payload")
+)
+
+// decodeJSON ignores fields a schema does not declare, as Protobuf decoding
+// does, so that producers can add fields before the schema that declares
+// them is deployed, and so that legacy payloads keep decoding when they
+// carry fields that were never part of a schema.
+var decodeJSON = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+// schemaFiles are the schemas the service is built with, compiled from the
+// .proto sources next to them by make schemas.
+//
+//go:embed schemas
+var schemaFiles embed.FS
+
+var schemaFileName = regexp.MustCompile(`^([a-z0-9_]+)/v([1-9][0-9]*)\.binpb$`)
+
+type schemaKey struct {
+	subject string
+	version int
+}
+
+// Registry is a file based stand-in for a schema registry. It holds one
+// Protobuf message descriptor per subject and version, read from files laid
+// out as <subject>/v<version>.binpb, each a descriptor set whose file
+// <subject>/v<version>.proto declares the schema as its first message. JSON
+// payloads are checked against the same descriptors as Protobuf ones.
+type Registry struct {
+	schemas map[schemaKey]protoreflect.MessageDescriptor
+}
+
+// NewFileRegistry reads every schema in fsys.
+func NewFileRegistry(fsys fs.FS) (*Registry, error) {
+	r := &Registry{schemas: make(map[schemaKey]protoreflect.MessageDescriptor)}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		match := schemaFileName.FindStringSubmatch(name)
+		if match == nil {
+			return nil
+		}
+
+		version, _ := strconv.Atoi(match[2])
+		desc, err := readSchema(fsys, name)
+		if err != nil {
+			return fmt.Errorf("schema %s: %w", name, err)
+		}
+
+		r.schemas[schemaKey{subject: match[1], version: version}] = desc
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// DefaultRegistry returns a registry of the schemas the service is built with.
+func DefaultRegistry() *Registry {
+	fsys, err := fs.Sub(schemaFiles, "schemas")
+	if err != nil {
+		panic(err)
+	}
+
+	r, err := NewFileRegistry(fsys)
+	if err != nil {
+		panic(err)
+	}
+
+	return r
+}
+
+func readSchema(fsys fs.FS, name string) (protoreflect.MessageDescriptor, error) {
+	raw, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(raw, &set); err != nil {
+		return nil, err
+	}
+
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, err
+	}
+
+	source := name[:len(name)-len(path.Ext(name))] + ".proto"
+	file, err := files.FindFileByPath(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if file.Messages().Len() == 0 {
+		return nil, fmt.Errorf("%s declares no message", source)
+	}
+
+	return file.Messages().Get(0), nil
+}
+
+// Decode checks the payload of m against its schema for subject and stores
+// it in v, which is filled in like by encoding/json using the schema's field
+// names. Fields the schema does not declare are ignored. Errors wrap
+// ErrUnknownSchema when the payload's encoding or version is not known, and
+// ErrInvalidPayload when it does not match its schema.
+func (r *Registry) Decode(subject string, m *kafka.Message, v any) error {
+	contentType, version, err := payloadType(subject, m)
+	if err != nil {
+		return err
+	}
+
+	desc, ok := r.schemas[schemaKey{subject: subject, version: version}]
+	if !ok {
+		return fmt.Errorf("%w: %s version %d", ErrUnknownSchema, subject, version)
+	}
+
+	msg := dynamicpb.NewMessage(desc)
+	switch contentType {
+	case ContentTypeJSON:
+		err = decodeJSON.Unmarshal(m.Value, msg)
+	case ContentTypeProtobuf:
+		err = proto.Unmarshal(m.Value, msg)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+	}
+
+	decoded, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+	}
+
+	if err := json.Unmarshal(decoded, v); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+	}
+
+	return nil
+}
+
+// Encode turns v, which is read like by encoding/json using the schema's
+// field names, into a payload of the given content type and schema version.
+// It returns the payload along with the content-type header to send it with.
+func (r *Registry) Encode(subject string, version int, contentType string, v any) ([]byte, string, error) {
+	desc, ok := r.schemas[schemaKey{subject: subject, version: version}]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %s version %d", ErrUnknownSchema, subject, version)
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, "", err
+	}
+
+	msg := dynamicpb.NewMessage(desc)
+	if err := protojson.Unmarshal(raw, msg); err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+	}
+
+	var payload []byte
+	switch contentType {
+	case ContentTypeJSON:
+		payload, err = protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	case ContentTypeProtobuf:
+		payload, err = proto.Marshal(msg)
+	default:
+		return nil, "", fmt.Errorf("%w: content type %q", ErrUnknownSchema, contentType)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	header := mime.FormatMediaType(contentType, map[string]string{
+		"schema":  subject,
+		"version": strconv.Itoa(version),
+	})
+
+	return payload, header, nil
+}
+
+// payloadType reads the encoding and schema version of m from its
+// content-type header.
+func payloadType(subject string, m *kafka.Message) (string, int, error) {
+	var header string
+	for _, h := range m.Headers {
+		if h.Key == ContentTypeHeader {
+			header = string(h.Value)
+		}
+	}
+
+	if header == "" {
+		return ContentTypeJSON, LegacySchemaVersion, nil
+	}
+
+	contentType, params, err := mime.ParseMediaType(header)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: content type %q: %w", ErrUnknownSchema, header, err)
+	}
+
+	if contentType != ContentTypeJSON && contentType != ContentTypeProtobuf {
+		return "", 0, fmt.Errorf("%w: content type %q", ErrUnknownSchema, contentType)
+	}
+
+	if schema, ok := params["schema"]; ok && schema != subject {
+		return "", 0, fmt.Errorf("%w: expected %s, got %s", ErrUnknownSchema, subject, schema)
+	}
+
+	version := LegacySchemaVersion
+	if v, ok := params["version"]; ok {
+		version, err = strconv.Atoi(v)
+		if err != nil {
+			return "", 0, fmt.Errorf("%w: version %q", ErrUnknownSchema, v)
+		}
+	}
+
+	return contentType, version, nil
+}
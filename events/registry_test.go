@@ -0,0 +1,147 @@
+package events_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/zero-shubham/surveysvc/events"
+)
+
+type answer struct {
+	UserID          string   `json:"user_id"`
+	QuestionID      string   `json:"question_id"`
+	QuestionSetID   string   `json:"question_set_id"`
+	SelectedOption  string   `json:"selected_option"`
+	SelectedOptions []string `json:"selected_options"`
+	AnswerText      string   `json:"answer_text"`
+	CampaignID      string   `json:"campaign_id"`
+}
+
+var submitted = answer{
+	UserID:          "4f7c6f4e-2a55-4b9f-9d2e-6f1c3a0b8e11",
+	QuestionID:      "0b3d2c1a-6e5f-4a7b-8c9d-1e2f3a4b5c6d",
+	QuestionSetID:   "9a8b7c6d-5e4f-4321-8fed-cba987654321",
+	SelectedOptions: []string{"red", "blue"},
+	CampaignID:      "c0ffee00-1234-4abc-9def-0123456789ab",
+}
+
+func message(value string, contentType string) *kafka.Message {
+	m := &kafka.Message{Value: []byte(value)}
+	if contentType != "" {
+		m.Headers = []kafka.Header{{Key: events.ContentTypeHeader, Value: []byte(contentType)}}
+	}
+	return m
+}
+
+func equal(a, b answer) bool {
+	return a.UserID == b.UserID &&
+		a.QuestionID == b.QuestionID &&
+		a.QuestionSetID == b.QuestionSetID &&
+		a.SelectedOption == b.SelectedOption &&
+		slices.Equal(a.SelectedOptions, b.SelectedOptions) &&
+		a.AnswerText == b.AnswerText &&
+		a.CampaignID == b.CampaignID
+}
+
+func TestDecodeLegacyJSON(t *testing.T) {
+	r := events.DefaultRegistry()
+
+	value := `{
+		"user_id": "4f7c6f4e-2a55-4b9f-9d2e-6f1c3a0b8e11",
+		"question_id": "0b3d2c1a-6e5f-4a7b-8c9d-1e2f3a4b5c6d",
+		"question_set_id": "9a8b7c6d-5e4f-4321-8fed-cba987654321",
+		"selected_options": ["red", "blue"],
+		"campaign_id": "c0ffee00-1234-4abc-9def-0123456789ab",
+		"source": "mobile",
+		"submitted_at": {"seconds": 1700000000}
+	}`
+
+	var got answer
+	if err := r.Decode(events.AnswerSubmittedSubject, message(value, ""), &got); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if !equal(got, submitted) {
+		t.Errorf("Decode() = %+v, want %+v", got, submitted)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	r := events.DefaultRegistry()
+
+	for _, contentType := range []string{events.ContentTypeJSON, events.ContentTypeProtobuf} {
+		t.Run(contentType, func(t *testing.T) {
+			payload, header, err := r.Encode(events.AnswerSubmittedSubject, 1, contentType, submitted)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+
+			var got answer
+			if err := r.Decode(events.AnswerSubmittedSubject, message(string(payload), header), &got); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+
+			if !equal(got, submitted) {
+				t.Errorf("Decode() = %+v, want %+v", got, submitted)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	r := events.DefaultRegistry()
+
+	tests := []struct {
+		name        string
+		value       string
+		contentType string
+		want        error
+	}{
+		{
+			name:        "unknown version",
+			value:       `{}`,
+			contentType: "application/json; schema=answer_submitted; version=2",
+			want:        events.ErrUnknownSchema,
+		},
+		{
+			name:        "other subject",
+			value:       `{}`,
+			contentType: "application/json; schema=response_started; version=1",
+			want:        events.ErrUnknownSchema,
+		},
+		{
+			name:        "unknown content type",
+			value:       `user_id=1`,
+			contentType: "application/x-www-form-urlencoded",
+			want:        events.ErrUnknownSchema,
+		},
+		{
+			name:  "malformed JSON",
+			value: `{"user_id": `,
+			want:  events.ErrInvalidPayload,
+		},
+		{
+			name:  "mistyped field",
+			value: `{"user_id": 42}`,
+			want:  events.ErrInvalidPayload,
+		},
+		{
+			name:        "malformed protobuf",
+			value:       "\x0a\xff",
+			contentType: "application/x-protobuf; version=1",
+			want:        events.ErrInvalidPayload,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got answer
+			err := r.Decode(events.AnswerSubmittedSubject, message(tt.value, tt.contentType), &got)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Decode() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
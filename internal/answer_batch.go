@@ -0,0 +1,291 @@
+package internal
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+)
+
+// MaxBatchAnswers caps how many answers are taken in a single batch.
+const MaxBatchAnswers = 100
+
+// ErrBatchAborted is reported for the answers of an atomic batch that were
+// not stored because another answer in the batch was rejected.
+var ErrBatchAborted = errors.New("answer was not stored because another answer in the batch was rejected")
+
+// AnswerResult is the outcome of one answer of a batch. Err is set when the
+// answer was rejected and holds the error CreateAnswer would have returned.
+type AnswerResult struct {
+	Answer  db.Answer
+	Created bool
+	Err     error
+}
+
+// IsRejection reports whether err rejects an answer, as opposed to being a
+// failure to process it.
+func IsRejection(err error) bool {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return true
+	}
+
+	for _, rejection := range []error{
+		ErrQuestionNotFound,
+		ErrQuestionSetNotFound,
+		ErrQuestionSetNotPublished,
+		ErrQuestionNotInSet,
+		ErrCampaignNotFound,
+		ErrCampaignNotOpen,
+		ErrResponseNotFound,
+		ErrResponseClosed,
+		ErrResponseMismatch,
+		ErrQuestionSkipped,
+		ErrDuplicateAnswer,
+		ErrIdempotencyKeyReused,
+	} {
+		if errors.Is(err, rejection) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CreateAnswers checks and stores a batch of answers in one transaction,
+// reporting the outcome of each in the order given. Answers are checked as
+// CreateAnswer checks them, with earlier answers of the batch counting as
+// given when following the branching of a response. Rejected answers do not
+// keep the others from being stored unless atomic is set, in which case
+// nothing is stored and the answers that were fine fail with ErrBatchAborted.
+// Only rejections, for which IsRejection holds, are reported per answer. Any
+// other failure, such as a database error, is returned for the batch as a
+// whole and nothing is stored, atomic or not, since the answers share a
+// transaction that the failure aborts.
+func (s *Service) CreateAnswers(ctx context.Context, ins []AnswerInput, atomic bool) ([]AnswerResult, error) {
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Answers are checked in the transaction they are stored in, which keeps
+	// their campaigns and responses from being closed until they are.
+	txOrm := db.New(s.conn).WithTx(tx)
+
+	results := make([]AnswerResult, len(ins))
+	prepared := make([]AnswerInput, len(ins))
+	policies := make([]db.AnswerPolicy, len(ins))
+	pending := pendingAnswers{}
+	var accepted []int
+	rejected := false
+
+	for i, in := range ins {
+		if in.IdempotencyKey != "" {
+			answer, found, err := answerByIdempotencyKey(ctx, txOrm, in)
+			if IsRejection(err) {
+				results[i].Err = err
+				rejected = true
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if found {
+				results[i].Answer = answer
+				continue
+			}
+		}
+
+		in, policy, err := prepareAnswer(ctx, txOrm, in, pending)
+		if IsRejection(err) {
+			results[i].Err = err
+			rejected = true
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		pending.add(in)
+		prepared[i], policies[i] = in, policy
+		accepted = append(accepted, i)
+	}
+
+	if atomic && rejected {
+		return abortBatch(results, accepted), nil
+	}
+
+	// Answers that may be given any number of times are inserted together
+	// through a single pgx batch. The others go one by one since they have to
+	// look at what the user answered before.
+	var batched []int
+	var params []db.CreateAnswerBatchParams
+	for _, i := range accepted {
+		if policies[i] == db.AnswerPolicyAllowMultiple {
+			batched = append(batched, i)
+			params = append(params, db.CreateAnswerBatchParams(answerParams(prepared[i])))
+			continue
+		}
+
+		answer, created, err := writeAnswer(ctx, txOrm, policies[i], prepared[i])
+		if IsRejection(err) {
+			results[i].Err = err
+			rejected = true
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		results[i] = AnswerResult{Answer: answer, Created: created}
+	}
+
+	if atomic && rejected {
+		return abortBatch(results, accepted), nil
+	}
+
+	var conflicts []int
+	if len(params) > 0 {
+		txOrm.CreateAnswerBatch(ctx, params).QueryRow(func(j int, answer db.Answer, batchErr error) {
+			i := batched[j]
+			switch {
+			case errors.Is(batchErr, pgx.ErrNoRows):
+				// The idempotency key was taken by an earlier answer of the
+				// batch or by a concurrent submission.
+				conflicts = append(conflicts, i)
+			case batchErr != nil:
+				if err == nil {
+					err = batchErr
+				}
+			default:
+				results[i] = AnswerResult{Answer: answer, Created: true}
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, i := range conflicts {
+		answer, _, err := answerByIdempotencyKey(ctx, txOrm, prepared[i])
+		if IsRejection(err) {
+			results[i].Err = err
+			rejected = true
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		results[i] = AnswerResult{Answer: answer}
+	}
+
+	if atomic && rejected {
+		return abortBatch(results, accepted), nil
+	}
+
+	if err := recordBatchEvents(ctx, txOrm, results, batched); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// abortBatch marks the accepted answers of a batch that was not stored.
+func abortBatch(results []AnswerResult, accepted []int) []AnswerResult {
+	for _, i := range accepted {
+		if results[i].Err == nil {
+			results[i] = AnswerResult{Err: ErrBatchAborted}
+		}
+	}
+
+	return results
+}
+
+// recordBatchEvents records the answer_created events of the answers of a
+// batch that were inserted together, and announces them to live subscribers.
+// Answers written one by one recorded their events already.
+func recordBatchEvents(ctx context.Context, orm *db.Queries, results []AnswerResult, batched []int) error {
+	var events []db.CreateOutboxEventBatchParams
+	var deliveries []db.EnqueueWebhookDeliveriesBatchParams
+	var changes []string
+	orgs := make(map[uuid.UUID]uuid.NullUUID)
+
+	for _, i := range batched {
+		if !results[i].Created {
+			continue
+		}
+
+		answer := results[i].Answer
+		event, err := outboxEvent(ctx, AnswerAggregate, answer.ID, AnswerCreatedEvent, answer)
+		if err != nil {
+			return err
+		}
+		events = append(events, db.CreateOutboxEventBatchParams(event))
+
+		if !answer.CampaignID.Valid {
+			continue
+		}
+
+		change, err := answerNotice(answer, true)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, change)
+
+		org, ok := orgs[answer.CampaignID.UUID]
+		if !ok {
+			org, err = campaignOrg(ctx, orm, answer.CampaignID)
+			if err != nil {
+				return err
+			}
+			orgs[answer.CampaignID.UUID] = org
+		}
+
+		deliveries = append(deliveries, db.EnqueueWebhookDeliveriesBatchParams{
+			EventType: AnswerCreatedEvent,
+			Payload:   event.Payload,
+			OrgID:     org.UUID,
+		})
+	}
+
+	var err error
+	if len(events) > 0 {
+		orm.CreateOutboxEventBatch(ctx, events).Exec(func(_ int, batchErr error) {
+			if batchErr != nil && err == nil {
+				err = batchErr
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(deliveries) > 0 {
+		orm.EnqueueWebhookDeliveriesBatch(ctx, deliveries).Exec(func(_ int, batchErr error) {
+			if batchErr != nil && err == nil {
+				err = batchErr
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(changes) > 0 {
+		orm.NotifyAnswerChangeBatch(ctx, changes).Exec(func(_ int, batchErr error) {
+			if batchErr != nil && err == nil {
+				err = batchErr
+			}
+		})
+	}
+
+	return err
+}
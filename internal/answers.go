@@ -0,0 +1,461 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+)
+
+var (
+	// ErrAnswerNotFound is returned when an answer that does not exist is
+	// looked up or edited.
+	ErrAnswerNotFound = errors.New("answer not found")
+
+	// ErrDuplicateAnswer is returned when the user already answered the
+	// question and the answer policy rejects duplicates.
+	ErrDuplicateAnswer = errors.New("question was already answered")
+
+	// ErrIdempotencyKeyReused is returned when an idempotency key the user
+	// already stored an answer under comes with a different answer.
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different answer")
+)
+
+// AnswerInput is an answer as submitted through either the API or the
+// consumer, before it has been checked against its question.
+type AnswerInput struct {
+	SelectedOption  string
+	SelectedOptions []string
+	AnswerText      string
+	UserID          uuid.UUID
+	QuestionID      uuid.UUID
+	QuestionSetID   uuid.UUID
+	CampaignID      uuid.NullUUID
+	ResponseID      uuid.NullUUID
+
+	// IdempotencyKey identifies a submission of the user across retries, and
+	// is namespaced by ClientIdempotencyKey or by the consumer. Submitting
+	// again with a key that was already stored returns the stored answer.
+	IdempotencyKey string
+}
+
+// Options merges the legacy single selected_option into selected_options so
+// that both ways of choosing an option are handled alike.
+func (in AnswerInput) Options() []string {
+	if in.SelectedOption == "" || slices.Contains(in.SelectedOptions, in.SelectedOption) {
+		return in.SelectedOptions
+	}
+
+	return append([]string{in.SelectedOption}, in.SelectedOptions...)
+}
+
+// ClientIdempotencyKey namespaces an idempotency key chosen by an API client,
+// so that it cannot collide with the keys the consumer derives from messages.
+func ClientIdempotencyKey(key string) string {
+	if key == "" {
+		return ""
+	}
+
+	return "client/" + key
+}
+
+// fingerprint identifies what was submitted, to tell a replay from a
+// different answer sent under the same idempotency key. A response decides
+// the campaign of its answers, so the campaign is left out when there is one
+// and the fingerprint is the same before and after prepareAnswer fills it in.
+func (in AnswerInput) fingerprint() string {
+	campaignID := in.CampaignID
+	if in.ResponseID.Valid {
+		campaignID = uuid.NullUUID{}
+	}
+
+	options := in.Options()
+	if options == nil {
+		options = []string{}
+	}
+
+	data, _ := json.Marshal([]any{in.UserID, in.QuestionID, in.QuestionSetID, campaignID, in.ResponseID, options, in.AnswerText})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// answerParams is what gets stored for an answer. Every chosen option goes in
+// selected_options, and selected_option keeps carrying the choice whenever
+// there is exactly one so that older readers still see it.
+func answerParams(in AnswerInput) db.CreateAnswerParams {
+	selected := in.Options()
+	if selected == nil {
+		selected = []string{}
+	}
+
+	var selectedOption pgtype.Text
+	if len(selected) == 1 {
+		selectedOption = pgtype.Text{String: selected[0], Valid: true}
+	}
+
+	params := db.CreateAnswerParams{
+		SelectedOption:  selectedOption,
+		SelectedOptions: selected,
+		AnswerText:      pgtype.Text{String: in.AnswerText, Valid: in.AnswerText != ""},
+		UserID:          in.UserID,
+		QuestionID:      in.QuestionID,
+		QuestionSetID:   in.QuestionSetID,
+		CampaignID:      in.CampaignID,
+		ResponseID:      in.ResponseID,
+		IdempotencyKey:  pgtype.Text{String: in.IdempotencyKey, Valid: in.IdempotencyKey != ""},
+	}
+
+	if in.IdempotencyKey != "" {
+		params.IdempotencyFingerprint = pgtype.Text{String: in.fingerprint(), Valid: true}
+	}
+
+	return params
+}
+
+// ResolveAnswer fetches a stored answer.
+func ResolveAnswer(ctx context.Context, orm *db.Queries, answerID uuid.UUID) (db.Answer, error) {
+	answer, err := orm.GetAnswerByID(ctx, answerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Answer{}, ErrAnswerNotFound
+	}
+
+	return answer, err
+}
+
+// answerByIdempotencyKey fetches the answer the user stored under the
+// idempotency key of in, reporting false if there is none. It fails with
+// ErrIdempotencyKeyReused if the stored answer was submitted with different
+// content.
+func answerByIdempotencyKey(ctx context.Context, orm *db.Queries, in AnswerInput) (db.Answer, bool, error) {
+	// A key that cannot be stored was never stored; the answer is rejected
+	// when it is validated.
+	if !storableText(in.IdempotencyKey) {
+		return db.Answer{}, false, nil
+	}
+
+	answer, err := orm.GetAnswerByIdempotencyKey(ctx, db.GetAnswerByIdempotencyKeyParams{
+		UserID:         in.UserID,
+		IdempotencyKey: pgtype.Text{String: in.IdempotencyKey, Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Answer{}, false, nil
+	}
+	if err != nil {
+		return db.Answer{}, false, err
+	}
+
+	// Answers stored before fingerprints were recorded cannot be compared.
+	if answer.IdempotencyFingerprint.Valid && answer.IdempotencyFingerprint.String != in.fingerprint() {
+		return db.Answer{}, false, ErrIdempotencyKeyReused
+	}
+
+	return answer, true, nil
+}
+
+// CreateAnswer resolves the question being answered, validates the answer
+// against it, checks that its question set is published and contains it,
+// that the campaign, if any, is open and that the response, if any, is still
+// in progress and has not branched past the question, and stores the answer
+// under the answer policy of the campaign, or else of the question set.
+// An answer whose idempotency key the user sent before is not checked again;
+// the answer stored first under that key is returned, reporting false, or
+// ErrIdempotencyKeyReused if it was a different answer. The checks run in the
+// transaction the answer is stored in, which keeps its campaign and response
+// from being closed until it is.
+func (s *Service) CreateAnswer(ctx context.Context, in AnswerInput) (db.Answer, bool, error) {
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return db.Answer{}, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	orm := db.New(s.conn).WithTx(tx)
+
+	if in.IdempotencyKey != "" {
+		answer, found, err := answerByIdempotencyKey(ctx, orm, in)
+		if err != nil || found {
+			return answer, false, err
+		}
+	}
+
+	in, policy, err := prepareAnswer(ctx, orm, in, nil)
+	if err != nil {
+		return db.Answer{}, false, err
+	}
+
+	answer, created, err := writeAnswer(ctx, orm, policy, in)
+	if err != nil {
+		return db.Answer{}, false, err
+	}
+
+	return answer, created, tx.Commit(ctx)
+}
+
+// pendingAnswers holds answers accepted earlier in a batch that are not yet
+// stored, keyed by response and question.
+type pendingAnswers map[uuid.UUID]map[uuid.UUID]db.Answer
+
+// add records an accepted answer that belongs to a response.
+func (p pendingAnswers) add(in AnswerInput) {
+	if p == nil || !in.ResponseID.Valid {
+		return
+	}
+
+	if p[in.ResponseID.UUID] == nil {
+		p[in.ResponseID.UUID] = make(map[uuid.UUID]db.Answer)
+	}
+
+	params := answerParams(in)
+	p[in.ResponseID.UUID][in.QuestionID] = db.Answer{
+		QuestionID:      in.QuestionID,
+		SelectedOptions: params.SelectedOptions,
+		AnswerText:      params.AnswerText,
+	}
+}
+
+// prepareAnswer runs every check an answer has to pass before it is stored
+// and returns it along with the answer policy it is stored under. Answers in
+// pending count as given when following the branching of their response. orm
+// has to be in the transaction the answer is stored in, for the campaign and
+// response it was checked against to stay open until then.
+func prepareAnswer(ctx context.Context, orm *db.Queries, in AnswerInput, pending pendingAnswers) (AnswerInput, db.AnswerPolicy, error) {
+	question, err := ResolveQuestion(ctx, orm, in.QuestionID)
+	if err != nil {
+		return in, "", err
+	}
+
+	if err := ValidateAnswer(question, in); err != nil {
+		return in, "", err
+	}
+
+	if in.ResponseID.Valid {
+		response, err := resolveOpenResponse(ctx, orm, in.ResponseID.UUID)
+		if err != nil {
+			return in, "", err
+		}
+
+		if !in.CampaignID.Valid {
+			in.CampaignID = response.CampaignID
+		}
+
+		if response.UserID != in.UserID || response.QuestionSetID != in.QuestionSetID || response.CampaignID != in.CampaignID {
+			return in, "", ErrResponseMismatch
+		}
+
+		if err := checkReachable(ctx, orm, response, in, pending); err != nil {
+			return in, "", err
+		}
+	}
+
+	questionSet, err := checkAnswerable(ctx, orm, in.QuestionSetID, in.QuestionID)
+	if err != nil {
+		return in, "", err
+	}
+
+	policy := questionSet.AnswerPolicy
+	if in.CampaignID.Valid {
+		campaign, err := resolveOpenCampaign(ctx, orm, in.CampaignID.UUID)
+		if err != nil {
+			return in, "", err
+		}
+
+		if campaign.AnswerPolicy.Valid {
+			policy = campaign.AnswerPolicy.AnswerPolicy
+		}
+	}
+
+	return in, policy, nil
+}
+
+// checkReachable fails with ErrQuestionSkipped if the branching of a response,
+// following the answers it has along with those in pending, has moved past
+// the question of in.
+func checkReachable(ctx context.Context, orm *db.Queries, response db.Response, in AnswerInput, pending pendingAnswers) error {
+	flow, err := loadFlow(ctx, orm, response.QuestionSetID)
+	if err != nil {
+		return err
+	}
+
+	answers, err := responseAnswers(ctx, orm, response.ID)
+	if err != nil {
+		return err
+	}
+
+	for questionID, answer := range pending[response.ID] {
+		answers[questionID] = answer
+	}
+
+	if !flow.Reachable(answers, in.QuestionID) {
+		return ErrQuestionSkipped
+	}
+
+	return nil
+}
+
+// writeAnswer stores an answer according to the answer policy in effect and
+// must run inside a transaction. Under keep_latest an earlier answer by the
+// user to the same question is overwritten, reporting false, and under
+// reject_duplicates it makes the answer fail with ErrDuplicateAnswer. Every
+// answer it stores or overwrites is recorded in the outbox.
+func writeAnswer(ctx context.Context, orm *db.Queries, policy db.AnswerPolicy, in AnswerInput) (db.Answer, bool, error) {
+	params := answerParams(in)
+
+	if policy != db.AnswerPolicyAllowMultiple {
+		err := orm.LockUserAnswers(ctx, fmt.Sprintf("answers/%s/%s/%s/%s", in.UserID, in.QuestionSetID, in.QuestionID, in.CampaignID.UUID))
+		if err != nil {
+			return db.Answer{}, false, err
+		}
+
+		existing, err := orm.GetLatestUserAnswer(ctx, db.GetLatestUserAnswerParams{
+			UserID:        in.UserID,
+			QuestionID:    in.QuestionID,
+			QuestionSetID: in.QuestionSetID,
+			CampaignID:    in.CampaignID,
+		})
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return db.Answer{}, false, err
+		}
+
+		if err == nil {
+			if policy == db.AnswerPolicyRejectDuplicates {
+				return db.Answer{}, false, ErrDuplicateAnswer
+			}
+
+			answer, err := orm.UpdateAnswerByID(ctx, db.UpdateAnswerByIDParams{
+				ID:              existing.ID,
+				SelectedOption:  params.SelectedOption,
+				SelectedOptions: params.SelectedOptions,
+				AnswerText:      params.AnswerText,
+				ResponseID:      params.ResponseID,
+			})
+			if err != nil {
+				return db.Answer{}, false, err
+			}
+
+			return answer, false, recordAnswerEvent(ctx, orm, answer, false)
+		}
+	}
+
+	answer, err := orm.CreateAnswer(ctx, params)
+	if errors.Is(err, pgx.ErrNoRows) && in.IdempotencyKey != "" {
+		// A concurrent submission with the same key was stored first.
+		answer, _, err = answerByIdempotencyKey(ctx, orm, in)
+		return answer, false, err
+	}
+	if err != nil {
+		return db.Answer{}, false, err
+	}
+
+	return answer, true, recordAnswerEvent(ctx, orm, answer, true)
+}
+
+// AnswerChange is an edit to the content of a stored answer. Nil fields keep
+// their stored value, and setting either option field replaces the whole
+// selection.
+type AnswerChange struct {
+	SelectedOption  *string
+	SelectedOptions *[]string
+	AnswerText      *string
+}
+
+// UpdateAnswer edits an answer in place. The edited answer is validated like a
+// new one, its question set, campaign and response must still accept answers,
+// and the branching of its response must not have moved past its question.
+func (s *Service) UpdateAnswer(ctx context.Context, answerID uuid.UUID, change AnswerChange) (db.Answer, error) {
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return db.Answer{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	orm := db.New(s.conn).WithTx(tx)
+	answer, err := orm.GetAnswerByIDForUpdate(ctx, answerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Answer{}, ErrAnswerNotFound
+	}
+	if err != nil {
+		return db.Answer{}, err
+	}
+
+	in := AnswerInput{
+		SelectedOptions: answer.SelectedOptions,
+		AnswerText:      answer.AnswerText.String,
+		UserID:          answer.UserID,
+		QuestionID:      answer.QuestionID,
+		QuestionSetID:   answer.QuestionSetID,
+		CampaignID:      answer.CampaignID,
+		ResponseID:      answer.ResponseID,
+	}
+
+	if change.SelectedOption != nil || change.SelectedOptions != nil {
+		in.SelectedOption, in.SelectedOptions = "", nil
+		if change.SelectedOption != nil {
+			in.SelectedOption = *change.SelectedOption
+		}
+		if change.SelectedOptions != nil {
+			in.SelectedOptions = *change.SelectedOptions
+		}
+	}
+
+	if change.AnswerText != nil {
+		in.AnswerText = *change.AnswerText
+	}
+
+	question, err := ResolveQuestion(ctx, orm, in.QuestionID)
+	if err != nil {
+		return db.Answer{}, err
+	}
+
+	if err := ValidateAnswer(question, in); err != nil {
+		return db.Answer{}, err
+	}
+
+	_, err = checkAnswerable(ctx, orm, in.QuestionSetID, in.QuestionID)
+	if err != nil {
+		return db.Answer{}, err
+	}
+
+	if in.CampaignID.Valid {
+		_, err := resolveOpenCampaign(ctx, orm, in.CampaignID.UUID)
+		if err != nil {
+			return db.Answer{}, err
+		}
+	}
+
+	if in.ResponseID.Valid {
+		response, err := resolveOpenResponse(ctx, orm, in.ResponseID.UUID)
+		if err != nil {
+			return db.Answer{}, err
+		}
+
+		if err := checkReachable(ctx, orm, response, in, nil); err != nil {
+			return db.Answer{}, err
+		}
+	}
+
+	params := answerParams(in)
+	answer, err = orm.UpdateAnswerByID(ctx, db.UpdateAnswerByIDParams{
+		ID:              answer.ID,
+		SelectedOption:  params.SelectedOption,
+		SelectedOptions: params.SelectedOptions,
+		AnswerText:      params.AnswerText,
+		ResponseID:      params.ResponseID,
+	})
+	if err != nil {
+		return db.Answer{}, err
+	}
+
+	if err := recordAnswerEvent(ctx, orm, answer, false); err != nil {
+		return db.Answer{}, err
+	}
+
+	return answer, tx.Commit(ctx)
+}
@@ -0,0 +1,300 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+)
+
+// ErrQuestionSkipped is returned when an answer is given to a question that
+// the branching rules of its question set skip for this response.
+var ErrQuestionSkipped = errors.New("question is skipped by the branching rules of the question set")
+
+// RuleInput is a branching rule as submitted for a question set. A nil
+// TargetQuestionID ends the question set when the rule matches.
+type RuleInput struct {
+	QuestionID       uuid.UUID
+	Operator         db.RuleOperator
+	Value            string
+	TargetQuestionID uuid.NullUUID
+}
+
+// ValidateRules checks rules against the ordered questions of their set. Both
+// ends of a rule must be in the set and rules may only jump forward, which
+// keeps every flow finite. Operators must make sense for the question they
+// test and their value must be comparable to its answers.
+func ValidateRules(questions []db.Question, rules []RuleInput) error {
+	var verr ValidationError
+
+	position := make(map[uuid.UUID]int, len(questions))
+	for i, question := range questions {
+		position[question.ID] = i
+	}
+
+	for i, rule := range rules {
+		field := func(name string) string {
+			return fmt.Sprintf("rules[%d].%s", i, name)
+		}
+
+		source, ok := position[rule.QuestionID]
+		if !ok {
+			verr.add(field("question_id"), "is not part of the question set")
+			continue
+		}
+		question := questions[source]
+
+		if rule.TargetQuestionID.Valid {
+			target, ok := position[rule.TargetQuestionID.UUID]
+			if !ok {
+				verr.add(field("target_question_id"), "is not part of the question set")
+			} else if target <= source {
+				verr.add(field("target_question_id"), "must come after the question the rule tests")
+			}
+		}
+
+		switch rule.Operator {
+		case db.RuleOperatorEquals, db.RuleOperatorNotEquals:
+			if HasOptions(question.QuestionType) && !slices.Contains(question.Options, rule.Value) {
+				verr.add(field("value"), "%q is not an option of the question", rule.Value)
+			}
+		case db.RuleOperatorIncludes:
+			if question.QuestionType != db.QuestionTypeMultiChoice {
+				verr.add(field("operator"), "includes only applies to multi_choice questions")
+			} else if !slices.Contains(question.Options, rule.Value) {
+				verr.add(field("value"), "%q is not an option of the question", rule.Value)
+			}
+		case db.RuleOperatorGreaterThan, db.RuleOperatorLessThan:
+			if question.QuestionType != db.QuestionTypeRating && question.QuestionType != db.QuestionTypeNumeric {
+				verr.add(field("operator"), "%s only applies to rating and numeric questions", rule.Operator)
+			} else if _, err := strconv.ParseFloat(rule.Value, 64); err != nil {
+				verr.add(field("value"), "must be a number")
+			}
+		default:
+			verr.add(field("operator"), "unknown operator")
+		}
+	}
+
+	return verr.errOrNil()
+}
+
+// Flow evaluates the branching rules of a question set. Questions are asked
+// in order unless a rule on an answered question matches, in which case the
+// first matching rule decides which question follows or ends the set.
+type Flow struct {
+	questions []db.Question
+	position  map[uuid.UUID]int
+	rules     map[uuid.UUID][]db.QuestionSetRule
+}
+
+// NewFlow builds the flow of a question set from its ordered questions and
+// rules.
+func NewFlow(questions []db.Question, rules []db.QuestionSetRule) *Flow {
+	f := Flow{
+		questions: questions,
+		position:  make(map[uuid.UUID]int, len(questions)),
+		rules:     make(map[uuid.UUID][]db.QuestionSetRule),
+	}
+
+	for i, question := range questions {
+		f.position[question.ID] = i
+	}
+
+	for _, rule := range rules {
+		f.rules[rule.QuestionID] = append(f.rules[rule.QuestionID], rule)
+	}
+
+	return &f
+}
+
+// Next returns the first question along the flow that has not been answered,
+// or false once the flow has reached its end.
+func (f *Flow) Next(answers map[uuid.UUID]db.Answer) (db.Question, bool) {
+	var next db.Question
+	found := false
+
+	f.walk(answers, func(question db.Question, answered bool) bool {
+		if answered {
+			return true
+		}
+		next, found = question, true
+		return false
+	})
+
+	return next, found
+}
+
+// Reachable reports whether a question can be answered given the answers so
+// far: it was answered along the flow or it is the next question.
+func (f *Flow) Reachable(answers map[uuid.UUID]db.Answer, questionID uuid.UUID) bool {
+	reachable := false
+
+	f.walk(answers, func(question db.Question, answered bool) bool {
+		if question.ID == questionID {
+			reachable = true
+			return false
+		}
+		return answered
+	})
+
+	return reachable
+}
+
+// MissingRequired lists the required questions along the flow that have not
+// been answered, stepping past unanswered optional questions in order.
+func (f *Flow) MissingRequired(answers map[uuid.UUID]db.Answer) []db.Question {
+	var missing []db.Question
+
+	f.walk(answers, func(question db.Question, answered bool) bool {
+		if !answered && question.Required {
+			missing = append(missing, question)
+		}
+		return true
+	})
+
+	return missing
+}
+
+// walk visits the questions along the flow until visit returns false or the
+// flow ends. Unanswered questions are followed by the next one in order.
+func (f *Flow) walk(answers map[uuid.UUID]db.Answer, visit func(question db.Question, answered bool) bool) {
+	i := 0
+	for i < len(f.questions) {
+		question := f.questions[i]
+		answer, answered := answers[question.ID]
+		if !visit(question, answered) {
+			return
+		}
+
+		if !answered {
+			i++
+			continue
+		}
+
+		next, end := f.follow(i, answer)
+		if end {
+			return
+		}
+		i = next
+	}
+}
+
+// follow returns the index of the question after the answered question at i,
+// or true if the flow ends there. Rules that would jump backwards, which can
+// only happen after questions were reordered, are ignored.
+func (f *Flow) follow(i int, answer db.Answer) (int, bool) {
+	for _, rule := range f.rules[f.questions[i].ID] {
+		if !ruleMatches(rule, answer) {
+			continue
+		}
+
+		if !rule.TargetQuestionID.Valid {
+			return 0, true
+		}
+
+		target, ok := f.position[rule.TargetQuestionID.UUID]
+		if ok && target > i {
+			return target, false
+		}
+	}
+
+	return i + 1, false
+}
+
+// ruleMatches tests an answer against a rule. Answers left empty, which is how
+// optional questions are skipped, match no rule.
+func ruleMatches(rule db.QuestionSetRule, answer db.Answer) bool {
+	if len(answer.SelectedOptions) == 0 && answer.AnswerText.String == "" {
+		return false
+	}
+
+	value := answer.AnswerText.String
+	if len(answer.SelectedOptions) > 0 {
+		value = strings.Join(answer.SelectedOptions, ",")
+	}
+
+	switch rule.Operator {
+	case db.RuleOperatorEquals:
+		return value == rule.Value
+	case db.RuleOperatorNotEquals:
+		return value != rule.Value
+	case db.RuleOperatorIncludes:
+		return slices.Contains(answer.SelectedOptions, rule.Value)
+	case db.RuleOperatorGreaterThan, db.RuleOperatorLessThan:
+		got, err := strconv.ParseFloat(strings.TrimSpace(answer.AnswerText.String), 64)
+		if err != nil {
+			return false
+		}
+		want, err := strconv.ParseFloat(rule.Value, 64)
+		if err != nil {
+			return false
+		}
+		if rule.Operator == db.RuleOperatorGreaterThan {
+			return got > want
+		}
+		return got < want
+	}
+
+	return false
+}
+
+// loadFlow fetches the questions and rules of a question set.
+func loadFlow(ctx context.Context, orm *db.Queries, questionSetID uuid.UUID) (*Flow, error) {
+	questions, err := orm.GetQuestionSetQuestions(ctx, questionSetID)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := orm.GetQuestionSetRules(ctx, questionSetID)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFlow(questions, rules), nil
+}
+
+// responseAnswers fetches the answers of a response, keyed by question. Only
+// the latest answer to each question counts.
+func responseAnswers(ctx context.Context, orm *db.Queries, responseID uuid.UUID) (map[uuid.UUID]db.Answer, error) {
+	answers, err := orm.GetAnswersByResponseID(ctx, uuid.NullUUID{UUID: responseID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+
+	byQuestion := make(map[uuid.UUID]db.Answer, len(answers))
+	for _, answer := range answers {
+		byQuestion[answer.QuestionID] = answer
+	}
+
+	return byQuestion, nil
+}
+
+// NextQuestion evaluates the branching rules of a response's question set
+// against the answers given so far. It returns the question to ask next, or
+// false once the response has nothing left to answer.
+func (s *Service) NextQuestion(ctx context.Context, responseID uuid.UUID) (db.Question, bool, error) {
+	orm := db.New(s.conn)
+
+	response, err := ResolveResponse(ctx, orm, responseID)
+	if err != nil {
+		return db.Question{}, false, err
+	}
+
+	flow, err := loadFlow(ctx, orm, response.QuestionSetID)
+	if err != nil {
+		return db.Question{}, false, err
+	}
+
+	answers, err := responseAnswers(ctx, orm, response.ID)
+	if err != nil {
+		return db.Question{}, false, err
+	}
+
+	question, ok := flow.Next(answers)
+	return question, ok, nil
+}
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+)
+
+var (
+	// ErrCampaignNotFound is returned when an answer refers to a campaign that
+	// does not exist.
+	ErrCampaignNotFound = errors.New("campaign not found")
+
+	// ErrCampaignNotOpen is returned when an answer arrives for a campaign
+	// that is not live at the time it is received.
+	ErrCampaignNotOpen = errors.New("campaign is not open for answers")
+)
+
+// campaignTransitions lists the statuses a campaign may be moved to by hand
+// from each status. Scheduled campaigns additionally go live on their own at
+// starts_at, and every open campaign closes on its own at ends_at.
+var campaignTransitions = map[db.CampaignStatus][]db.CampaignStatus{
+	db.CampaignStatusDraft:     {db.CampaignStatusScheduled, db.CampaignStatusLive, db.CampaignStatusClosed},
+	db.CampaignStatusScheduled: {db.CampaignStatusDraft, db.CampaignStatusLive, db.CampaignStatusClosed},
+	db.CampaignStatusLive:      {db.CampaignStatusPaused, db.CampaignStatusClosed},
+	db.CampaignStatusPaused:    {db.CampaignStatusLive, db.CampaignStatusClosed},
+	db.CampaignStatusClosed:    {},
+}
+
+// CanTransitionCampaign reports whether a campaign may move from one status
+// to another.
+func CanTransitionCampaign(from db.CampaignStatus, to db.CampaignStatus) bool {
+	return slices.Contains(campaignTransitions[from], to)
+}
+
+// ResolveCampaign fetches the campaign an answer refers to.
+func ResolveCampaign(ctx context.Context, orm *db.Queries, campaignID uuid.UUID) (db.Campaign, error) {
+	campaign, err := orm.GetCampaignByID(ctx, campaignID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Campaign{}, ErrCampaignNotFound
+	}
+
+	return campaign, err
+}
+
+// resolveOpenCampaign fetches a campaign that accepts answers right now. The
+// campaign is share locked for the rest of orm's transaction, so that it
+// cannot be closed before the answers checked against it are stored.
+func resolveOpenCampaign(ctx context.Context, orm *db.Queries, campaignID uuid.UUID) (db.Campaign, error) {
+	campaign, err := orm.GetCampaignByIDForShare(ctx, campaignID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Campaign{}, ErrCampaignNotFound
+	}
+	if err != nil {
+		return db.Campaign{}, err
+	}
+
+	return campaign, CheckCampaignOpen(campaign, time.Now())
+}
+
+// EffectiveCampaignStatus is the status of the campaign at the given time,
+// taking its schedule into account even if the scheduler has not yet
+// persisted the change.
+func EffectiveCampaignStatus(campaign db.Campaign, now time.Time) db.CampaignStatus {
+	status := campaign.Status
+	if status == db.CampaignStatusClosed || status == db.CampaignStatusDraft {
+		return status
+	}
+
+	if campaign.EndsAt.Valid && !now.Before(campaign.EndsAt.Time) {
+		return db.CampaignStatusClosed
+	}
+
+	if status == db.CampaignStatusScheduled && campaign.StartsAt.Valid && !now.Before(campaign.StartsAt.Time) {
+		return db.CampaignStatusLive
+	}
+
+	return status
+}
+
+// CheckCampaignOpen returns an error wrapping ErrCampaignNotOpen unless the
+// campaign accepts answers at the given time.
+func CheckCampaignOpen(campaign db.Campaign, now time.Time) error {
+	status := EffectiveCampaignStatus(campaign, now)
+	if status != db.CampaignStatusLive {
+		return fmt.Errorf("%w: campaign is %s", ErrCampaignNotOpen, status)
+	}
+
+	if campaign.StartsAt.Valid && now.Before(campaign.StartsAt.Time) {
+		return fmt.Errorf("%w: campaign opens at %s", ErrCampaignNotOpen, campaign.StartsAt.Time.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// RunCampaignScheduler persists scheduled status changes, opening campaigns
+// whose start has passed and closing those whose end has passed, until ctx is
+// done.
+func (s *Service) RunCampaignScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	orm := db.New(s.conn)
+	for {
+		opened, err := orm.OpenScheduledCampaigns(ctx)
+		if err != nil {
+			s.logger.Err(err).Msg("failed to open scheduled campaigns")
+		}
+
+		closed, err := orm.CloseEndedCampaigns(ctx)
+		if err != nil {
+			s.logger.Err(err).Msg("failed to close ended campaigns")
+		}
+
+		if opened > 0 || closed > 0 {
+			s.logger.Info().Int64("opened", opened).Int64("closed", closed).Msg("applied campaign schedules")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
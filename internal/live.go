@@ -0,0 +1,383 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+)
+
+const (
+	// AnswerChangesChannel is the Postgres channel answers are announced on
+	// when they are written, by whichever process writes them.
+	AnswerChangesChannel = "answer_changes"
+
+	// answerChangeBuffer is how many updates a subscriber may fall behind by
+	// before it is dropped.
+	answerChangeBuffer = 64
+
+	// answerTallyInterval is how often tallies are refreshed for the
+	// questions answered since the last ones.
+	answerTallyInterval = time.Second
+)
+
+// ErrLiveUnavailable is returned when subscribing to answers on a service
+// that was not given an answer feed.
+var ErrLiveUnavailable = errors.New("live answers are not available")
+
+// AnswerNotice announces that an answer was written. It only identifies the
+// answer, since notifications are limited in size.
+type AnswerNotice struct {
+	ID         uuid.UUID `json:"id"`
+	CampaignID uuid.UUID `json:"campaign_id"`
+	QuestionID uuid.UUID `json:"question_id"`
+	Created    bool      `json:"created"`
+}
+
+func answerNotice(answer db.Answer, created bool) (string, error) {
+	payload, err := json.Marshal(AnswerNotice{
+		ID:         answer.ID,
+		CampaignID: answer.CampaignID.UUID,
+		QuestionID: answer.QuestionID,
+		Created:    created,
+	})
+	return string(payload), err
+}
+
+// notifyAnswerChange announces an answer to the processes listening on
+// AnswerChangesChannel once the transaction of orm commits. Answers outside
+// of campaigns cannot be followed live and are not announced.
+func notifyAnswerChange(ctx context.Context, orm *db.Queries, answer db.Answer, created bool) error {
+	if !answer.CampaignID.Valid {
+		return nil
+	}
+
+	payload, err := answerNotice(answer, created)
+	if err != nil {
+		return err
+	}
+
+	return orm.NotifyAnswerChange(ctx, payload)
+}
+
+// Listener hands out connections that can be taken over to listen for
+// notifications, as *pgxpool.Pool does.
+type Listener interface {
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+}
+
+// LiveUpdate is what the subscribers of a campaign receive: either an answer
+// written to the campaign, or the refreshed tallies of the questions answered
+// since the last tallies.
+type LiveUpdate struct {
+	Answer  *db.Answer
+	Tallies []QuestionTally
+}
+
+// AnswerFeed listens for the answers announced on AnswerChangesChannel and
+// fans them out to the subscribers of their campaign. Each answer is read once
+// however many subscribers it goes to, and the tallies of each campaign are
+// refreshed at most every answerTallyInterval, and only for campaigns that
+// have subscribers. Changes announced while the feed is reconnecting are
+// missed, so subscribers should treat the feed as a hint to refresh rather
+// than as a complete log.
+type AnswerFeed struct {
+	logger *zerolog.Logger
+	conn   db.DBTX
+
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan LiveUpdate]struct{}
+	// answered holds, by campaign, the questions answered since the last
+	// tallies.
+	answered map[uuid.UUID]map[uuid.UUID]struct{}
+	closed   bool
+	done     chan struct{}
+}
+
+func NewAnswerFeed(logger *zerolog.Logger, conn db.DBTX) *AnswerFeed {
+	return &AnswerFeed{
+		logger:      logger,
+		conn:        conn,
+		subscribers: make(map[uuid.UUID]map[chan LiveUpdate]struct{}),
+		answered:    make(map[uuid.UUID]map[uuid.UUID]struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// WithAnswerFeed lets the service stream the answers the feed receives.
+func WithAnswerFeed(feed *AnswerFeed) ServiceOption {
+	return func(s *Service) {
+		s.feed = feed
+	}
+}
+
+// Run listens for answers until ctx is done, reconnecting after errors. Once
+// it returns, every subscription is closed, so that streams end when the
+// process shuts down rather than when their clients go away.
+func (f *AnswerFeed) Run(ctx context.Context, listener Listener) {
+	defer close(f.done)
+	defer f.close()
+
+	go f.tally(ctx)
+
+	for {
+		err := f.listen(ctx, listener)
+		if ctx.Err() != nil {
+			return
+		}
+
+		f.logger.Err(err).Msg("answer feed disconnected, reconnecting")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Done is closed once Run has returned and every subscription is closed.
+func (f *AnswerFeed) Done() <-chan struct{} {
+	return f.done
+}
+
+func (f *AnswerFeed) listen(ctx context.Context, listener Listener) error {
+	pooled, err := listener.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The connection keeps listening for as long as it is open, so it is
+	// taken out of the pool rather than handed back to it.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+AnswerChangesChannel); err != nil {
+		return err
+	}
+
+	f.logger.Info().Str("channel", AnswerChangesChannel).Msg("listening for answers")
+
+	orm := db.New(f.conn)
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var notice AnswerNotice
+		if err := json.Unmarshal([]byte(notification.Payload), &notice); err != nil {
+			f.logger.Err(err).Str("payload", notification.Payload).Msg("failed to read answer notice")
+			continue
+		}
+
+		if !f.subscribed(notice.CampaignID) {
+			continue
+		}
+
+		answer, err := ResolveAnswer(ctx, orm, notice.ID)
+		if errors.Is(err, ErrAnswerNotFound) {
+			// Deleted since it was announced.
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			f.logger.Err(err).Str("answer_id", notice.ID.String()).Msg("failed to fetch announced answer")
+			continue
+		}
+
+		f.mu.Lock()
+		f.publish(notice.CampaignID, LiveUpdate{Answer: &answer})
+		if f.answered[notice.CampaignID] == nil {
+			f.answered[notice.CampaignID] = make(map[uuid.UUID]struct{})
+		}
+		f.answered[notice.CampaignID][answer.QuestionID] = struct{}{}
+		f.mu.Unlock()
+	}
+}
+
+// tally sends the subscribers of each campaign the refreshed tallies of the
+// questions answered since the last ones, every answerTallyInterval until ctx
+// is done.
+func (f *AnswerFeed) tally(ctx context.Context) {
+	ticker := time.NewTicker(answerTallyInterval)
+	defer ticker.Stop()
+
+	orm := db.New(f.conn)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		f.mu.Lock()
+		answered := f.answered
+		f.answered = make(map[uuid.UUID]map[uuid.UUID]struct{})
+		f.mu.Unlock()
+
+		for campaignID, questions := range answered {
+			questionIDs := make([]uuid.UUID, 0, len(questions))
+			for questionID := range questions {
+				questionIDs = append(questionIDs, questionID)
+			}
+
+			tallies, err := answerTallies(ctx, orm, campaignID, questionIDs)
+			if err != nil {
+				f.logger.Err(err).Str("campaign_id", campaignID.String()).Msg("failed to tally answers")
+				continue
+			}
+
+			f.mu.Lock()
+			f.publish(campaignID, LiveUpdate{Tallies: tallies})
+			f.mu.Unlock()
+		}
+	}
+}
+
+// Subscribe returns the updates to a campaign, and a function to stop
+// receiving them. A subscriber that falls too far behind is dropped, which
+// closes its channel, as does the feed stopping.
+func (f *AnswerFeed) Subscribe(campaignID uuid.UUID) (<-chan LiveUpdate, func()) {
+	ch := make(chan LiveUpdate, answerChangeBuffer)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		close(ch)
+		return ch, func() {}
+	}
+
+	if f.subscribers[campaignID] == nil {
+		f.subscribers[campaignID] = make(map[chan LiveUpdate]struct{})
+	}
+	f.subscribers[campaignID][ch] = struct{}{}
+
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.drop(campaignID, ch)
+	}
+}
+
+func (f *AnswerFeed) subscribed(campaignID uuid.UUID) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.subscribers[campaignID]) > 0
+}
+
+// publish sends an update to the subscribers of a campaign. f.mu must be
+// held.
+func (f *AnswerFeed) publish(campaignID uuid.UUID, update LiveUpdate) {
+	for ch := range f.subscribers[campaignID] {
+		select {
+		case ch <- update:
+		default:
+			f.logger.Warn().Str("campaign_id", campaignID.String()).Msg("dropping slow answer subscriber")
+			f.drop(campaignID, ch)
+		}
+	}
+}
+
+// drop removes a subscriber, if it was not removed already. f.mu must be
+// held.
+func (f *AnswerFeed) drop(campaignID uuid.UUID, ch chan LiveUpdate) {
+	subscribers := f.subscribers[campaignID]
+	if _, ok := subscribers[ch]; !ok {
+		return
+	}
+
+	delete(subscribers, ch)
+	close(ch)
+
+	if len(subscribers) == 0 {
+		delete(f.subscribers, campaignID)
+		delete(f.answered, campaignID)
+	}
+}
+
+// close drops every subscriber and refuses new ones.
+func (f *AnswerFeed) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	for campaignID, subscribers := range f.subscribers {
+		for ch := range subscribers {
+			f.drop(campaignID, ch)
+		}
+	}
+}
+
+// SubscribeAnswers follows the answers written to a campaign by any process.
+func (s *Service) SubscribeAnswers(campaignID uuid.UUID) (<-chan LiveUpdate, func(), error) {
+	if s.feed == nil {
+		return nil, nil, ErrLiveUnavailable
+	}
+
+	updates, unsubscribe := s.feed.Subscribe(campaignID)
+	return updates, unsubscribe, nil
+}
+
+// QuestionTally is how often a question of a campaign was answered, and how
+// often each of its options was chosen.
+type QuestionTally struct {
+	QuestionID uuid.UUID        `json:"question_id"`
+	Answers    int64            `json:"answers"`
+	Options    map[string]int64 `json:"options"`
+}
+
+// AnswerTallies counts the answers of a campaign to the given questions, or
+// to all of its questions if none are given.
+func (s *Service) AnswerTallies(ctx context.Context, campaignID uuid.UUID, questionIDs []uuid.UUID) ([]QuestionTally, error) {
+	return answerTallies(ctx, db.New(s.conn), campaignID, questionIDs)
+}
+
+func answerTallies(ctx context.Context, orm *db.Queries, campaignID uuid.UUID, questionIDs []uuid.UUID) ([]QuestionTally, error) {
+	campaign := uuid.NullUUID{UUID: campaignID, Valid: true}
+
+	counts, err := orm.GetCampaignAnswerCounts(ctx, db.GetCampaignAnswerCountsParams{
+		CampaignID:  campaign,
+		QuestionIds: questionIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := orm.GetCampaignOptionCounts(ctx, db.GetCampaignOptionCountsParams{
+		CampaignID:  campaign,
+		QuestionIds: questionIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tallies := make([]QuestionTally, len(counts))
+	byQuestion := make(map[uuid.UUID]*QuestionTally, len(counts))
+	for i, count := range counts {
+		tallies[i] = QuestionTally{
+			QuestionID: count.QuestionID,
+			Answers:    count.Answers,
+			Options:    map[string]int64{},
+		}
+		byQuestion[count.QuestionID] = &tallies[i]
+	}
+
+	for _, option := range options {
+		if tally, ok := byQuestion[option.QuestionID]; ok {
+			tally.Options[option.Option] = option.Answers
+		}
+	}
+
+	return tallies, nil
+}
@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Events recorded in the outbox, by the aggregate they are about.
+const (
+	AnswerAggregate          = "answer"
+	ResponseAggregate        = "response"
+	QuestionMappingAggregate = "question_mapping"
+
+	AnswerCreatedEvent          = "answer_created"
+	AnswerUpdatedEvent          = "answer_updated"
+	QuestionMappingUpdatedEvent = "question_mapping_updated"
+)
+
+// EventTypes are the types of the events recorded in the outbox. Completed
+// responses are announced under the type of the event that completes them.
+var EventTypes = []string{
+	AnswerCreatedEvent,
+	AnswerUpdatedEvent,
+	ResponseCompletedEvent,
+	QuestionMappingUpdatedEvent,
+}
+
+// outboxEvent builds an outbox row carrying payload as JSON, along with the
+// trace context of ctx so that the published event continues the trace of
+// the request that caused it.
+func outboxEvent(ctx context.Context, aggregateType string, aggregateID uuid.UUID, eventType string, payload any) (db.CreateOutboxEventParams, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return db.CreateOutboxEventParams{}, err
+	}
+
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	headers, err := json.Marshal(carrier)
+	if err != nil {
+		return db.CreateOutboxEventParams{}, err
+	}
+
+	return db.CreateOutboxEventParams{
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       body,
+		Headers:       headers,
+	}, nil
+}
+
+// recordEvent writes an event to the outbox and, if it belongs to an org,
+// queues its delivery to the webhooks of the org subscribed to it. orm has to
+// be in the transaction of the change the event describes, so that the event
+// goes out if and only if the change is committed.
+func recordEvent(ctx context.Context, orm *db.Queries, org uuid.NullUUID, aggregateType string, aggregateID uuid.UUID, eventType string, payload any) error {
+	params, err := outboxEvent(ctx, aggregateType, aggregateID, eventType, payload)
+	if err != nil {
+		return err
+	}
+
+	if err := orm.CreateOutboxEvent(ctx, params); err != nil {
+		return err
+	}
+
+	if !org.Valid {
+		return nil
+	}
+
+	_, err = orm.EnqueueWebhookDeliveries(ctx, db.EnqueueWebhookDeliveriesParams{
+		EventType: eventType,
+		Payload:   params.Payload,
+		OrgID:     org.UUID,
+	})
+	return err
+}
+
+// recordAnswerEvent writes an answer_created or answer_updated event, and
+// announces the answer to live subscribers.
+func recordAnswerEvent(ctx context.Context, orm *db.Queries, answer db.Answer, created bool) error {
+	eventType := AnswerUpdatedEvent
+	if created {
+		eventType = AnswerCreatedEvent
+	}
+
+	org, err := campaignOrg(ctx, orm, answer.CampaignID)
+	if err != nil {
+		return err
+	}
+
+	if err := notifyAnswerChange(ctx, orm, answer, created); err != nil {
+		return err
+	}
+
+	return recordEvent(ctx, orm, org, AnswerAggregate, answer.ID, eventType, answer)
+}
+
+// campaignOrg returns the org running a campaign, for events about answers
+// and responses, which belong to an org only through their campaign.
+func campaignOrg(ctx context.Context, orm *db.Queries, campaignID uuid.NullUUID) (uuid.NullUUID, error) {
+	if !campaignID.Valid {
+		return uuid.NullUUID{}, nil
+	}
+
+	campaign, err := orm.GetCampaignByID(ctx, campaignID.UUID)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+
+	return uuid.NullUUID{UUID: campaign.OrgID, Valid: true}, nil
+}
@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+)
+
+// ErrQuestionMappingNotFound is returned when a question mapping that does not
+// exist is edited.
+var ErrQuestionMappingNotFound = errors.New("question mapping not found")
+
+// UpdateQuestionMapping edits a question mapping and records the change in
+// the outbox. A mapping that already matches params is returned as it is,
+// without recording an event, so that applying the same change twice is
+// harmless.
+func (s *Service) UpdateQuestionMapping(ctx context.Context, params db.UpdateQuestionMappingsByIDParams) (db.QuestionMapping, error) {
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return db.QuestionMapping{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	orm := db.New(s.conn).WithTx(tx)
+	qm, err := orm.GetQuestionMappingByIDForUpdate(ctx, params.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.QuestionMapping{}, ErrQuestionMappingNotFound
+	}
+	if err != nil {
+		return db.QuestionMapping{}, err
+	}
+
+	if qm.CampaignID == params.CampaignID && qm.QuestionID == params.QuestionID && qm.OrgID == params.OrgID {
+		return qm, nil
+	}
+
+	qm, err = orm.UpdateQuestionMappingsByID(ctx, params)
+	if err != nil {
+		return db.QuestionMapping{}, err
+	}
+
+	org := uuid.NullUUID{UUID: qm.OrgID, Valid: true}
+	err = recordEvent(ctx, orm, org, QuestionMappingAggregate, qm.ID, QuestionMappingUpdatedEvent, qm)
+	if err != nil {
+		return db.QuestionMapping{}, err
+	}
+
+	return qm, tx.Commit(ctx)
+}
@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+)
+
+var (
+	// ErrQuestionSetNotFound is returned when an answer refers to a question
+	// set that does not exist.
+	ErrQuestionSetNotFound = errors.New("question set not found")
+
+	// ErrQuestionSetNotPublished is returned when an answer is submitted to a
+	// question set that is still a draft or has been archived.
+	ErrQuestionSetNotPublished = errors.New("question set is not published")
+
+	// ErrQuestionNotInSet is returned when an answer's question is not a
+	// member of the question set it was submitted to.
+	ErrQuestionNotInSet = errors.New("question is not part of the question set")
+)
+
+// ResolveQuestionSet fetches the question set an answer refers to.
+func ResolveQuestionSet(ctx context.Context, orm *db.Queries, questionSetID uuid.UUID) (db.QuestionSet, error) {
+	questionSet, err := orm.GetQuestionSetByID(ctx, questionSetID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.QuestionSet{}, ErrQuestionSetNotFound
+	}
+
+	return questionSet, err
+}
+
+// checkAnswerable verifies that the question set accepts answers and that the
+// question belongs to it.
+func checkAnswerable(ctx context.Context, orm *db.Queries, questionSetID uuid.UUID, questionID uuid.UUID) (db.QuestionSet, error) {
+	questionSet, err := resolvePublishedQuestionSet(ctx, orm, questionSetID)
+	if err != nil {
+		return db.QuestionSet{}, err
+	}
+
+	member, err := orm.IsQuestionInSet(ctx, db.IsQuestionInSetParams{
+		QuestionSetID: questionSetID,
+		QuestionID:    questionID,
+	})
+	if err != nil {
+		return db.QuestionSet{}, err
+	}
+
+	if !member {
+		return db.QuestionSet{}, ErrQuestionNotInSet
+	}
+
+	return questionSet, nil
+}
+
+// resolvePublishedQuestionSet fetches a question set that accepts answers.
+func resolvePublishedQuestionSet(ctx context.Context, orm *db.Queries, questionSetID uuid.UUID) (db.QuestionSet, error) {
+	questionSet, err := ResolveQuestionSet(ctx, orm, questionSetID)
+	if err != nil {
+		return db.QuestionSet{}, err
+	}
+
+	if questionSet.Status != db.QuestionSetStatusPublished {
+		return db.QuestionSet{}, ErrQuestionSetNotPublished
+	}
+
+	return questionSet, nil
+}
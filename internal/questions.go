@@ -0,0 +1,30 @@
+package internal
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+)
+
+// ErrQuestionNotFound is returned when an answer refers to a question that is
+// not in the catalog.
+var ErrQuestionNotFound = errors.New("question not found")
+
+// ResolveQuestion fetches the question an answer refers to.
+func ResolveQuestion(ctx context.Context, orm *db.Queries, questionID uuid.UUID) (db.Question, error) {
+	question, err := orm.GetQuestionByID(ctx, questionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Question{}, ErrQuestionNotFound
+	}
+
+	return question, err
+}
+
+// HasOptions reports whether answers to questions of the given type are
+// picked from the question's list of options.
+func HasOptions(questionType db.QuestionType) bool {
+	return questionType == db.QuestionTypeSingleChoice || questionType == db.QuestionTypeMultiChoice
+}
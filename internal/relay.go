@@ -0,0 +1,216 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+	"github.com/segmentio/kafka-go"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+	"github.com/zero-shubham/surveysvc/events"
+	"github.com/zero-shubham/surveysvc/transport/messaging"
+)
+
+const (
+	// MaxOutboxAttempts is how many times the relay tries to publish an
+	// event before parking it. Parked events stay in the outbox, with the
+	// last error they failed with, until their attempts are reset.
+	MaxOutboxAttempts = 10
+
+	// outboxPurgeInterval is how often sent events past their retention are
+	// deleted.
+	outboxPurgeInterval = time.Minute
+)
+
+// MessageWriter publishes messages, as *kafka.Writer does.
+type MessageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// Relay publishes the events recorded in the outbox. An event is marked as
+// sent only once the broker acknowledged it, so every event is published at
+// least once; consumers see an event again if the relay stops between
+// publishing it and marking it. Events are published in the order they were
+// recorded, keyed by the aggregate they are about, except that an event which
+// fails to publish does not hold back the events after it, and is parked after
+// MaxOutboxAttempts.
+type Relay struct {
+	logger    *zerolog.Logger
+	conn      db.Conn
+	writer    MessageWriter
+	topics    map[string]string
+	batchSize int32
+}
+
+// NewRelay returns a relay publishing each event type to the topic topics
+// maps it to. Events of other types are left in the outbox.
+func NewRelay(logger *zerolog.Logger, conn db.Conn, writer MessageWriter, topics map[string]string, batchSize int) *Relay {
+	return &Relay{
+		logger:    logger,
+		conn:      conn,
+		writer:    writer,
+		topics:    topics,
+		batchSize: int32(batchSize),
+	}
+}
+
+// Run publishes outbox events every interval until ctx is done, right away
+// again as long as there are more waiting, and deletes events that were sent
+// more than retention ago.
+func (r *Relay) Run(ctx context.Context, interval time.Duration, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var purged time.Time
+	for {
+		sent, err := r.Publish(ctx)
+		if err != nil {
+			r.logger.Err(err).Msg("failed to publish outbox events")
+		}
+
+		if retention > 0 && time.Since(purged) >= outboxPurgeInterval {
+			r.purge(ctx, retention)
+			purged = time.Now()
+		}
+
+		if err == nil && sent == int(r.batchSize) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Publish sends one batch of outbox events and returns how many were sent,
+// along with the write error if any of them failed.
+// Several relays can run at once, each claiming the events it publishes, but
+// events of an aggregate are then only in order within what each relay
+// publishes.
+func (r *Relay) Publish(ctx context.Context) (int, error) {
+	eventTypes := make([]string, 0, len(r.topics))
+	for eventType := range r.topics {
+		eventTypes = append(eventTypes, eventType)
+	}
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	orm := db.New(r.conn).WithTx(tx)
+	rows, err := orm.ClaimOutboxEvents(ctx, db.ClaimOutboxEventsParams{
+		EventTypes:  eventTypes,
+		MaxAttempts: MaxOutboxAttempts,
+		MaxEvents:   r.batchSize,
+	})
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+
+	messages := make([]kafka.Message, len(rows))
+	for i, row := range rows {
+		messages[i] = r.message(row)
+	}
+
+	// The writer reports which messages failed when only some of them did,
+	// so that the others are marked sent rather than published again.
+	writeErr := r.writer.WriteMessages(ctx, messages...)
+	errs := make([]error, len(rows))
+	var writeErrs kafka.WriteErrors
+	if errors.As(writeErr, &writeErrs) && len(writeErrs) == len(rows) {
+		copy(errs, writeErrs)
+	} else if writeErr != nil {
+		for i := range errs {
+			errs[i] = writeErr
+		}
+	}
+
+	sent := make([]int64, 0, len(rows))
+	for i, row := range rows {
+		if errs[i] == nil {
+			sent = append(sent, row.ID)
+			continue
+		}
+
+		// Recorded so that events that keep failing can be found, and
+		// retried on a later run until they are parked.
+		err := orm.RecordOutboxFailure(ctx, db.RecordOutboxFailureParams{
+			Ids:       []int64{row.ID},
+			LastError: pgtype.Text{String: errs[i].Error(), Valid: true},
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		if row.Attempts+1 >= MaxOutboxAttempts {
+			r.logger.Error().Err(errs[i]).Int64("outbox_id", row.ID).Str("event_type", row.EventType).Msg("parking outbox event that keeps failing to publish")
+		}
+	}
+
+	if len(sent) > 0 {
+		if err := orm.MarkOutboxEventsSent(ctx, sent); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	if len(sent) > 0 {
+		r.logger.Info().Int("events", len(sent)).Ctx(ctx).Msg("published outbox events")
+	}
+
+	return len(sent), writeErr
+}
+
+// message turns an outbox row into the message it is published as, carrying
+// the trace context recorded with it. The row's ID is the event ID, so that
+// consumers recognise an event the relay published again after a failed
+// attempt to mark it.
+func (r *Relay) message(row db.Outbox) kafka.Message {
+	m := kafka.Message{
+		Topic: r.topics[row.EventType],
+		Key:   []byte(row.AggregateID.String()),
+		Value: row.Payload,
+		Headers: []kafka.Header{
+			{Key: events.ContentTypeHeader, Value: []byte(events.ContentTypeJSON)},
+			{Key: messaging.EventTypeHeader, Value: []byte(row.EventType)},
+			{Key: messaging.EventIDHeader, Value: []byte(strconv.FormatInt(row.ID, 10))},
+		},
+	}
+
+	var headers map[string]string
+	if err := json.Unmarshal(row.Headers, &headers); err != nil {
+		r.logger.Err(err).Int64("outbox_id", row.ID).Msg("failed to read outbox event headers")
+		return m
+	}
+
+	carrier := messaging.NewHeaderCarrier(&m)
+	for key, value := range headers {
+		carrier.Set(key, value)
+	}
+
+	return m
+}
+
+func (r *Relay) purge(ctx context.Context, retention time.Duration) {
+	deleted, err := db.New(r.conn).DeleteSentOutboxEvents(ctx, pgtype.Timestamptz{Time: time.Now().Add(-retention), Valid: true})
+	if err != nil {
+		r.logger.Err(err).Msg("failed to delete sent outbox events")
+		return
+	}
+
+	if deleted > 0 {
+		r.logger.Info().Int64("events", deleted).Msg("deleted sent outbox events")
+	}
+}
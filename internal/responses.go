@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+)
+
+var (
+	// ErrResponseNotFound is returned when a response does not exist.
+	ErrResponseNotFound = errors.New("response not found")
+
+	// ErrResponseClosed is returned when a response that has been completed
+	// or abandoned is answered or closed again.
+	ErrResponseClosed = errors.New("response is no longer in progress")
+
+	// ErrResponseMismatch is returned when an answer names a response that
+	// belongs to another user, question set or campaign.
+	ErrResponseMismatch = errors.New("answer does not match the user, question set or campaign of its response")
+)
+
+// ResponseInput identifies the user, question set and optional campaign a
+// response is opened for.
+type ResponseInput struct {
+	UserID        uuid.UUID
+	QuestionSetID uuid.UUID
+	CampaignID    uuid.NullUUID
+}
+
+// ResolveResponse fetches a response by ID.
+func ResolveResponse(ctx context.Context, orm *db.Queries, responseID uuid.UUID) (db.Response, error) {
+	response, err := orm.GetResponseByID(ctx, responseID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Response{}, ErrResponseNotFound
+	}
+
+	return response, err
+}
+
+// resolveOpenResponse fetches a response that still accepts answers. The
+// response is share locked for the rest of orm's transaction, so that it
+// cannot be closed before the answers checked against it are stored.
+func resolveOpenResponse(ctx context.Context, orm *db.Queries, responseID uuid.UUID) (db.Response, error) {
+	response, err := orm.GetResponseByIDForShare(ctx, responseID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Response{}, ErrResponseNotFound
+	}
+	if err != nil {
+		return db.Response{}, err
+	}
+
+	if response.Status != db.ResponseStatusInProgress {
+		return db.Response{}, ErrResponseClosed
+	}
+
+	return response, nil
+}
+
+// StartResponse opens a response for the user on a published question set and
+// open campaign. A user has at most one response in progress per question set
+// and campaign, so if one exists it is returned to be resumed and started is
+// false.
+func (s *Service) StartResponse(ctx context.Context, in ResponseInput) (response db.Response, started bool, err error) {
+	orm := db.New(s.conn)
+	params := db.GetOpenResponseParams(in)
+
+	response, err = orm.GetOpenResponse(ctx, params)
+	if err == nil {
+		return response, false, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return db.Response{}, false, err
+	}
+
+	_, err = resolvePublishedQuestionSet(ctx, orm, in.QuestionSetID)
+	if err != nil {
+		return db.Response{}, false, err
+	}
+
+	if in.CampaignID.Valid {
+		_, err := resolveOpenCampaign(ctx, orm, in.CampaignID.UUID)
+		if err != nil {
+			return db.Response{}, false, err
+		}
+	}
+
+	response, err = orm.CreateResponse(ctx, db.CreateResponseParams(in))
+	if db.IsUniqueViolation(err) {
+		// Another request opened the response first, resume that one.
+		response, err = orm.GetOpenResponse(ctx, params)
+		return response, false, err
+	}
+	if err != nil {
+		return db.Response{}, false, err
+	}
+
+	return response, true, nil
+}
+
+// CompleteResponse submits a response. It fails with a ValidationError naming
+// each required question along the flow of the set that has not been
+// answered.
+func (s *Service) CompleteResponse(ctx context.Context, responseID uuid.UUID) (db.Response, error) {
+	return s.closeResponse(ctx, responseID, func(orm *db.Queries, response db.Response) (db.Response, error) {
+		flow, err := loadFlow(ctx, orm, response.QuestionSetID)
+		if err != nil {
+			return db.Response{}, err
+		}
+
+		answers, err := responseAnswers(ctx, orm, response.ID)
+		if err != nil {
+			return db.Response{}, err
+		}
+
+		var verr ValidationError
+		for _, question := range flow.MissingRequired(answers) {
+			verr.add(question.ID.String(), "an answer is required")
+		}
+		if err := verr.errOrNil(); err != nil {
+			return db.Response{}, err
+		}
+
+		response, err = orm.CompleteResponse(ctx, response.ID)
+		if err != nil {
+			return db.Response{}, err
+		}
+
+		org, err := campaignOrg(ctx, orm, response.CampaignID)
+		if err != nil {
+			return db.Response{}, err
+		}
+
+		err = recordEvent(ctx, orm, org, ResponseAggregate, response.ID, ResponseCompletedEvent, response)
+		return response, err
+	})
+}
+
+// AbandonResponse closes a response the user dropped out of.
+func (s *Service) AbandonResponse(ctx context.Context, responseID uuid.UUID) (db.Response, error) {
+	return s.closeResponse(ctx, responseID, func(orm *db.Queries, response db.Response) (db.Response, error) {
+		return orm.AbandonResponse(ctx, response.ID)
+	})
+}
+
+// closeResponse locks a response that is in progress and hands it to close
+// within a transaction.
+func (s *Service) closeResponse(
+	ctx context.Context,
+	responseID uuid.UUID,
+	close func(orm *db.Queries, response db.Response) (db.Response, error),
+) (db.Response, error) {
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return db.Response{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	orm := db.New(s.conn).WithTx(tx)
+	response, err := orm.GetResponseByIDForUpdate(ctx, responseID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Response{}, ErrResponseNotFound
+	}
+	if err != nil {
+		return db.Response{}, err
+	}
+
+	if response.Status != db.ResponseStatusInProgress {
+		return db.Response{}, ErrResponseClosed
+	}
+
+	response, err = close(orm, response)
+	if err != nil {
+		return db.Response{}, err
+	}
+
+	return response, tx.Commit(ctx)
+}
@@ -0,0 +1,228 @@
+package internal
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+)
+
+// ResultsFilter narrows the answers results are computed from. Zero fields
+// do not filter.
+type ResultsFilter struct {
+	CampaignID uuid.NullUUID
+	OrgID      uuid.NullUUID
+
+	// From and To bound when answers were given, From inclusive and To
+	// exclusive.
+	From time.Time
+	To   time.Time
+}
+
+// OptionResult is how often an option was chosen, and the percentage of
+// responses that chose it. Percentages of multi_choice questions add up to
+// more than 100.
+type OptionResult struct {
+	Option     string  `json:"option"`
+	Count      int64   `json:"count"`
+	Percentage float64 `json:"percentage"`
+}
+
+// QuestionResults aggregates the answers given to a question.
+type QuestionResults struct {
+	QuestionID   uuid.UUID       `json:"question_id"`
+	QuestionType db.QuestionType `json:"question_type"`
+	Responses    int64           `json:"responses"`
+	Respondents  int64           `json:"respondents"`
+	Options      []OptionResult  `json:"options"`
+}
+
+// QuestionResults counts the answers given to a question and how often each
+// of its options was chosen. Every option of the question is listed, in the
+// question's order, followed by options that were chosen but have since been
+// removed from it.
+func (s *Service) QuestionResults(ctx context.Context, questionID uuid.UUID, filter ResultsFilter) (QuestionResults, error) {
+	orm := db.New(s.conn)
+
+	question, err := ResolveQuestion(ctx, orm, questionID)
+	if err != nil {
+		return QuestionResults{}, err
+	}
+
+	from := pgtype.Timestamptz{Time: filter.From, Valid: !filter.From.IsZero()}
+	to := pgtype.Timestamptz{Time: filter.To, Valid: !filter.To.IsZero()}
+
+	counts, err := orm.GetQuestionResponseCounts(ctx, db.GetQuestionResponseCountsParams{
+		QuestionID:   questionID,
+		CampaignID:   filter.CampaignID,
+		OrgID:        filter.OrgID,
+		AnsweredFrom: from,
+		AnsweredTo:   to,
+	})
+	if err != nil {
+		return QuestionResults{}, err
+	}
+
+	optionCounts, err := orm.GetQuestionOptionCounts(ctx, db.GetQuestionOptionCountsParams{
+		QuestionID:   questionID,
+		CampaignID:   filter.CampaignID,
+		OrgID:        filter.OrgID,
+		AnsweredFrom: from,
+		AnsweredTo:   to,
+	})
+	if err != nil {
+		return QuestionResults{}, err
+	}
+
+	results := QuestionResults{
+		QuestionID:   question.ID,
+		QuestionType: question.QuestionType,
+		Responses:    counts.Responses,
+		Respondents:  counts.Respondents,
+	}
+
+	chosen := make([]optionCount, len(optionCounts))
+	for i, count := range optionCounts {
+		chosen[i] = optionCount{option: count.Option, count: count.Answers}
+	}
+	results.Options = optionResults(question.Options, chosen, counts.Responses)
+
+	return results, nil
+}
+
+// optionCount is how often an option was chosen.
+type optionCount struct {
+	option string
+	count  int64
+}
+
+func optionResults(options []string, counts []optionCount, responses int64) []OptionResult {
+	chosen := make(map[string]int64, len(counts))
+	for _, count := range counts {
+		chosen[count.option] = count.count
+	}
+
+	results := make([]OptionResult, 0, len(options))
+	for _, option := range options {
+		results = append(results, optionResult(option, chosen[option], responses))
+	}
+
+	// counts is ordered by how often options were chosen, which is the
+	// order removed options are listed in.
+	for _, count := range counts {
+		if !slices.Contains(options, count.option) {
+			results = append(results, optionResult(count.option, count.count, responses))
+		}
+	}
+
+	return results
+}
+
+func optionResult(option string, count int64, responses int64) OptionResult {
+	result := OptionResult{Option: option, Count: count}
+	if responses > 0 {
+		result.Percentage = float64(count) * 100 / float64(responses)
+	}
+
+	return result
+}
+
+// AnswerSummary is how many answers were given, by how many respondents, and
+// when the first and last of them were given.
+type AnswerSummary struct {
+	Responses       int64              `json:"responses"`
+	Respondents     int64              `json:"respondents"`
+	FirstAnsweredAt pgtype.Timestamptz `json:"first_answered_at"`
+	LastAnsweredAt  pgtype.Timestamptz `json:"last_answered_at"`
+}
+
+// QuestionSummary summarises the answers to a question within a campaign.
+type QuestionSummary struct {
+	QuestionID   uuid.UUID       `json:"question_id"`
+	QuestionType db.QuestionType `json:"question_type"`
+	AnswerSummary
+	Options []OptionResult `json:"options"`
+}
+
+// CampaignSummary summarises the answers given to a campaign as a whole and
+// to each question mapped to it.
+type CampaignSummary struct {
+	CampaignID uuid.UUID `json:"campaign_id"`
+	AnswerSummary
+	Questions []QuestionSummary `json:"questions"`
+}
+
+// CampaignSummary summarises how a campaign is performing. The totals count
+// every answer given to the campaign, including answers to questions that are
+// no longer mapped to it, which are left out of the per-question summaries.
+// Aggregates are computed by the database from the index on the campaign's
+// answers, rather than by reading the answers out.
+func (s *Service) CampaignSummary(ctx context.Context, campaignID uuid.UUID) (CampaignSummary, error) {
+	orm := db.New(s.conn)
+
+	if _, err := ResolveCampaign(ctx, orm, campaignID); err != nil {
+		return CampaignSummary{}, err
+	}
+
+	campaign := uuid.NullUUID{UUID: campaignID, Valid: true}
+
+	totals, err := orm.GetCampaignAnswerTotals(ctx, campaign)
+	if err != nil {
+		return CampaignSummary{}, err
+	}
+
+	rows, err := orm.GetCampaignQuestionSummaries(ctx, campaignID)
+	if err != nil {
+		return CampaignSummary{}, err
+	}
+
+	questionIDs := make([]uuid.UUID, len(rows))
+	for i, row := range rows {
+		questionIDs[i] = row.QuestionID
+	}
+
+	chosen := make(map[uuid.UUID][]optionCount, len(rows))
+	if len(questionIDs) > 0 {
+		optionCounts, err := orm.GetCampaignOptionCounts(ctx, db.GetCampaignOptionCountsParams{
+			CampaignID:  campaign,
+			QuestionIds: questionIDs,
+		})
+		if err != nil {
+			return CampaignSummary{}, err
+		}
+
+		for _, count := range optionCounts {
+			chosen[count.QuestionID] = append(chosen[count.QuestionID], optionCount{option: count.Option, count: count.Answers})
+		}
+	}
+
+	summary := CampaignSummary{
+		CampaignID: campaignID,
+		AnswerSummary: AnswerSummary{
+			Responses:       totals.Responses,
+			Respondents:     totals.Respondents,
+			FirstAnsweredAt: totals.FirstAnsweredAt,
+			LastAnsweredAt:  totals.LastAnsweredAt,
+		},
+		Questions: make([]QuestionSummary, len(rows)),
+	}
+
+	for i, row := range rows {
+		summary.Questions[i] = QuestionSummary{
+			QuestionID:   row.QuestionID,
+			QuestionType: row.QuestionType,
+			AnswerSummary: AnswerSummary{
+				Responses:       row.Responses,
+				Respondents:     row.Respondents,
+				FirstAnsweredAt: row.FirstAnsweredAt,
+				LastAnsweredAt:  row.LastAnsweredAt,
+			},
+			Options: optionResults(row.Options, chosen[row.QuestionID], row.Responses),
+		}
+	}
+
+	return summary, nil
+}
@@ -3,64 +3,393 @@ package internal
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog"
 	"github.com/segmentio/kafka-go"
 	db "github.com/zero-shubham/surveysvc/db/orm"
+	"github.com/zero-shubham/surveysvc/events"
+	"github.com/zero-shubham/surveysvc/transport/messaging"
 )
 
 type Service struct {
-	logger *zerolog.Logger
-	conn   db.DBTX
+	logger  *zerolog.Logger
+	conn    db.Conn
+	schemas *events.Registry
+	feed    *AnswerFeed
 }
 
-func NewService(logger *zerolog.Logger, conn db.DBTX) *Service {
-	return &Service{
+// ServiceOption configures optional behaviour of a Service.
+type ServiceOption func(*Service)
+
+// WithSchemaRegistry sets the schemas consumed messages are decoded with, in
+// place of those the service is built with.
+func WithSchemaRegistry(schemas *events.Registry) ServiceOption {
+	return func(s *Service) {
+		s.schemas = schemas
+	}
+}
+
+func NewService(logger *zerolog.Logger, conn db.Conn, opts ...ServiceOption) *Service {
+	s := &Service{
 		logger: logger,
 		conn:   conn,
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.schemas == nil {
+		s.schemas = events.DefaultRegistry()
+	}
+
+	return s
 }
 
+// MessageBody is an answer_submitted event, decoded from any of the versions
+// of its schema.
 type MessageBody struct {
-	UserID         uuid.UUID `json:"user_id"`
-	QuestionID     uuid.UUID `json:"question_id"`
-	QuestionSetID  uuid.UUID `json:"question_set_id"`
-	SelectedOption string    `json:"selected_option"`
-	AnswerText     string    `json:"answer_text"`
+	UserID          uuid.UUID     `json:"user_id"`
+	QuestionID      uuid.UUID     `json:"question_id"`
+	QuestionSetID   uuid.UUID     `json:"question_set_id"`
+	SelectedOption  string        `json:"selected_option"`
+	SelectedOptions []string      `json:"selected_options"`
+	AnswerText      string        `json:"answer_text"`
+	CampaignID      uuid.NullUUID `json:"campaign_id"`
+	ResponseID      uuid.NullUUID `json:"response_id"`
 }
 
-func (s *Service) HandleAnswer(ctx context.Context, message *kafka.Message) error {
+// decodeAnswer decodes the answer_submitted event carried by message.
+// Payloads that do not match a known schema, or that leave out whom and what
+// the answer is for, fail with a permanent error so that they are dead
+// lettered rather than retried.
+func (s *Service) decodeAnswer(message *kafka.Message) (MessageBody, error) {
 	var mb MessageBody
 
-	err := json.Unmarshal(message.Value, &mb)
+	err := s.schemas.Decode(events.AnswerSubmittedSubject, message, &mb)
+	if err != nil {
+		return mb, messaging.Permanent(err)
+	}
+
+	if mb.UserID == uuid.Nil || mb.QuestionID == uuid.Nil || mb.QuestionSetID == uuid.Nil {
+		return mb, messaging.Permanent(fmt.Errorf("%w: user_id, question_id and question_set_id are required", events.ErrInvalidPayload))
+	}
+
+	return mb, nil
+}
+
+// handlerError marks the errors that retrying a message cannot fix, answers
+// the service rejects and rows the database refuses, as permanent so that the
+// consumer sends the message to the dead letter topic without retrying it.
+func handlerError(err error) error {
+	if IsRejection(err) || db.IsIntegrityViolation(err) || db.IsDataException(err) {
+		return messaging.Permanent(err)
+	}
+
+	return err
+}
+
+// messageIdempotencyKey identifies an answer message across redeliveries. It
+// is the event ID the producer set, if any, and otherwise the message's
+// position in its topic. The message key is not used: it decides the
+// partition, and producers share it between the answers they want kept in
+// order.
+func messageIdempotencyKey(message *kafka.Message) string {
+	for _, header := range message.Headers {
+		if header.Key == messaging.EventIDHeader && len(header.Value) > 0 {
+			return "event/" + string(header.Value)
+		}
+	}
+
+	return fmt.Sprintf("message/%s/%d/%d", message.Topic, message.Partition, message.Offset)
+}
+
+func (s *Service) HandleAnswer(ctx context.Context, message *kafka.Message) error {
+	mb, err := s.decodeAnswer(message)
 	if err != nil {
 		s.logger.Err(err).Msg("failed to parse message body")
-		return nil
+		return err
 	}
 
-	orm := db.New(s.conn)
-
-	answer, err := orm.CreateAnswer(ctx, db.CreateAnswerParams{
-		AnswerText: pgtype.Text{
-			String: mb.AnswerText,
-			Valid:  true,
-		},
-		SelectedOption: pgtype.Text{
-			String: mb.SelectedOption,
-			Valid:  true,
-		},
-		UserID:        mb.UserID,
-		QuestionID:    mb.QuestionID,
-		QuestionSetID: mb.QuestionSetID,
+	answer, created, err := s.CreateAnswer(ctx, AnswerInput{
+		SelectedOption:  mb.SelectedOption,
+		SelectedOptions: mb.SelectedOptions,
+		AnswerText:      mb.AnswerText,
+		UserID:          mb.UserID,
+		QuestionID:      mb.QuestionID,
+		QuestionSetID:   mb.QuestionSetID,
+		CampaignID:      mb.CampaignID,
+		ResponseID:      mb.ResponseID,
+		IdempotencyKey:  messageIdempotencyKey(message),
 	})
 	if err != nil {
 		s.logger.Err(err).Msg("failed to create answer record")
-		return err
+		return handlerError(err)
+	}
+
+	if !created {
+		s.logger.Info().Str("answer_id", answer.ID.String()).Ctx(ctx).Msg("answer record already existed, kept the stored record")
+		return nil
 	}
 
 	s.logger.Info().Str("answer_id", answer.ID.String()).Ctx(ctx).Msg("successfully created answer record")
 
 	return nil
 }
+
+// HandleAnswers stores a batch of answer messages in one transaction and
+// returns the outcome of each message. A message with an unparsable body or a
+// rejected answer fails only itself, and a batch the database refuses as a
+// whole is failed retryably so that its messages are retried one by one.
+func (s *Service) HandleAnswers(ctx context.Context, messages []*kafka.Message) []error {
+	errs := make([]error, len(messages))
+
+	ins := make([]AnswerInput, 0, len(messages))
+	indexes := make([]int, 0, len(messages))
+	for i, message := range messages {
+		mb, err := s.decodeAnswer(message)
+		if err != nil {
+			s.logger.Err(err).Int64("offset", message.Offset).Msg("failed to parse message body")
+			errs[i] = err
+			continue
+		}
+
+		ins = append(ins, AnswerInput{
+			SelectedOption:  mb.SelectedOption,
+			SelectedOptions: mb.SelectedOptions,
+			AnswerText:      mb.AnswerText,
+			UserID:          mb.UserID,
+			QuestionID:      mb.QuestionID,
+			QuestionSetID:   mb.QuestionSetID,
+			CampaignID:      mb.CampaignID,
+			ResponseID:      mb.ResponseID,
+			IdempotencyKey:  messageIdempotencyKey(message),
+		})
+		indexes = append(indexes, i)
+	}
+
+	if len(ins) == 0 {
+		return errs
+	}
+
+	results, err := s.CreateAnswers(ctx, ins, false)
+	if err != nil {
+		s.logger.Err(err).Int("messages", len(ins)).Msg("failed to create answer records")
+		// The failure of a batch as a whole cannot be pinned on any one of
+		// its messages, so it is left retryable: each message is then retried
+		// on its own, which fails only the message at fault.
+		if len(ins) == 1 {
+			err = handlerError(err)
+		}
+		for _, i := range indexes {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	created := 0
+	for j, result := range results {
+		if result.Err != nil {
+			s.logger.Err(result.Err).Int64("offset", messages[indexes[j]].Offset).Msg("failed to create answer record")
+			errs[indexes[j]] = handlerError(result.Err)
+			continue
+		}
+
+		if result.Created {
+			created++
+		}
+	}
+
+	s.logger.Info().Int("messages", len(messages)).Int("created", created).Ctx(ctx).Msg("processed batch of answer records")
+
+	return errs
+}
+
+const (
+	ResponseStartedEvent   = "response_started"
+	ResponseCompletedEvent = "response_completed"
+	ResponseAbandonedEvent = "response_abandoned"
+)
+
+// ResponseEventBody opens or closes a response. Closing events name the
+// response by response_id, or else by the user, question set and campaign of
+// the response in progress.
+type ResponseEventBody struct {
+	EventType     string        `json:"event_type"`
+	ResponseID    uuid.NullUUID `json:"response_id"`
+	UserID        uuid.UUID     `json:"user_id"`
+	QuestionSetID uuid.UUID     `json:"question_set_id"`
+	CampaignID    uuid.NullUUID `json:"campaign_id"`
+}
+
+// HandleResponseEvent handles a response event of any type, taking the type
+// from the event_type of the body.
+func (s *Service) HandleResponseEvent(ctx context.Context, message *kafka.Message) error {
+	var eb ResponseEventBody
+
+	err := json.Unmarshal(message.Value, &eb)
+	if err != nil {
+		s.logger.Err(err).Msg("failed to parse message body")
+		return messaging.Permanent(err)
+	}
+
+	return s.handleResponseEvent(ctx, eb)
+}
+
+// ResponseEventHandler handles response events of one type, for topics that
+// carry the type in the event-type header. The body's event_type, if any, is
+// ignored.
+func (s *Service) ResponseEventHandler(eventType string) messaging.HandlerFunc {
+	return func(ctx context.Context, message *kafka.Message) error {
+		var eb ResponseEventBody
+
+		err := json.Unmarshal(message.Value, &eb)
+		if err != nil {
+			s.logger.Err(err).Str("event_type", eventType).Msg("failed to parse message body")
+			return messaging.Permanent(err)
+		}
+
+		eb.EventType = eventType
+		return s.handleResponseEvent(ctx, eb)
+	}
+}
+
+func (s *Service) handleResponseEvent(ctx context.Context, eb ResponseEventBody) error {
+	in := ResponseInput{
+		UserID:        eb.UserID,
+		QuestionSetID: eb.QuestionSetID,
+		CampaignID:    eb.CampaignID,
+	}
+
+	if eb.EventType == ResponseStartedEvent {
+		response, _, err := s.StartResponse(ctx, in)
+		if err != nil {
+			s.logger.Err(err).Msg("failed to start response")
+			return handlerError(err)
+		}
+
+		s.logger.Info().Str("response_id", response.ID.String()).Ctx(ctx).Msg("started response")
+		return nil
+	}
+
+	var close func(context.Context, uuid.UUID) (db.Response, error)
+	switch eb.EventType {
+	case ResponseCompletedEvent:
+		close = s.CompleteResponse
+	case ResponseAbandonedEvent:
+		close = s.AbandonResponse
+	default:
+		s.logger.Error().Str("event_type", eb.EventType).Msg("unknown response event type")
+		return messaging.Permanent(fmt.Errorf("unknown response event type %q", eb.EventType))
+	}
+
+	responseID := eb.ResponseID.UUID
+	if !eb.ResponseID.Valid {
+		response, err := db.New(s.conn).GetOpenResponse(ctx, db.GetOpenResponseParams(in))
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Warn().Str("event_type", eb.EventType).Msg("no response in progress to close")
+			return nil
+		}
+		if err != nil {
+			return handlerError(err)
+		}
+		responseID = response.ID
+	}
+
+	response, err := close(ctx, responseID)
+	if errors.Is(err, ErrResponseClosed) {
+		// Redelivered event, the response was already closed.
+		s.logger.Info().Str("response_id", responseID.String()).Msg("response already closed")
+		return nil
+	}
+	if err != nil {
+		s.logger.Err(err).Str("event_type", eb.EventType).Msg("failed to close response")
+		return handlerError(err)
+	}
+
+	s.logger.Info().Str("response_id", response.ID.String()).Str("status", string(response.Status)).Ctx(ctx).Msg("closed response")
+
+	return nil
+}
+
+const UserErasureRequestedEvent = "user_erasure_requested"
+
+// UserErasureBody asks for everything stored about a user to be deleted.
+type UserErasureBody struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (s *Service) HandleUserErasure(ctx context.Context, message *kafka.Message) error {
+	var eb UserErasureBody
+
+	err := json.Unmarshal(message.Value, &eb)
+	if err != nil {
+		s.logger.Err(err).Msg("failed to parse message body")
+		return messaging.Permanent(err)
+	}
+
+	if eb.UserID == uuid.Nil {
+		return messaging.Permanent(errors.New("user_id is required"))
+	}
+
+	answers, responses, err := s.EraseUser(ctx, eb.UserID)
+	if err != nil {
+		s.logger.Err(err).Msg("failed to erase user")
+		return handlerError(err)
+	}
+
+	s.logger.Info().Int64("answers", answers).Int64("responses", responses).Ctx(ctx).Msg("erased user")
+
+	return nil
+}
+
+const QuestionMappingChangedEvent = "question_mapping_changed"
+
+// QuestionMappingChangedBody moves an existing question mapping to the given
+// question, campaign and org.
+type QuestionMappingChangedBody struct {
+	ID         uuid.UUID `json:"id"`
+	QuestionID uuid.UUID `json:"question_id"`
+	CampaignID uuid.UUID `json:"campaign_id"`
+	OrgID      uuid.UUID `json:"org_id"`
+}
+
+// HandleQuestionMappingChange applies a question mapping change made
+// elsewhere. Changes that are already applied, such as redeliveries or the
+// service's own question_mapping_updated events read back, are ignored.
+func (s *Service) HandleQuestionMappingChange(ctx context.Context, message *kafka.Message) error {
+	var eb QuestionMappingChangedBody
+
+	err := json.Unmarshal(message.Value, &eb)
+	if err != nil {
+		s.logger.Err(err).Msg("failed to parse message body")
+		return messaging.Permanent(err)
+	}
+
+	if eb.ID == uuid.Nil || eb.QuestionID == uuid.Nil || eb.CampaignID == uuid.Nil || eb.OrgID == uuid.Nil {
+		return messaging.Permanent(errors.New("id, question_id, campaign_id and org_id are required"))
+	}
+
+	qm, err := s.UpdateQuestionMapping(ctx, db.UpdateQuestionMappingsByIDParams{
+		ID:         eb.ID,
+		QuestionID: eb.QuestionID,
+		CampaignID: eb.CampaignID,
+		OrgID:      eb.OrgID,
+	})
+	if errors.Is(err, ErrQuestionMappingNotFound) {
+		s.logger.Err(err).Str("question_mapping_id", eb.ID.String()).Msg("failed to update question mapping")
+		return messaging.Permanent(err)
+	}
+	if err != nil {
+		s.logger.Err(err).Str("question_mapping_id", eb.ID.String()).Msg("failed to update question mapping")
+		return handlerError(err)
+	}
+
+	s.logger.Info().Str("question_mapping_id", qm.ID.String()).Ctx(ctx).Msg("updated question mapping")
+
+	return nil
+}
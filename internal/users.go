@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+)
+
+// EraseUser deletes the answers and responses of a user, along with the
+// copies of them held by the outbox and by webhook deliveries, and returns how
+// many answers and responses were deleted. Erasing a user that has nothing
+// stored is not an error, so erasure requests can be redelivered safely.
+func (s *Service) EraseUser(ctx context.Context, userID uuid.UUID) (int64, int64, error) {
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	orm := db.New(s.conn).WithTx(tx)
+
+	answers, err := orm.DeleteAnswersByUserID(ctx, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	responses, err := orm.DeleteResponsesByUserID(ctx, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if _, err := orm.DeleteOutboxEventsByUserID(ctx, userID.String()); err != nil {
+		return 0, 0, err
+	}
+
+	if _, err := orm.DeleteWebhookDeliveriesByUserID(ctx, userID.String()); err != nil {
+		return 0, 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	return answers, responses, nil
+}
@@ -0,0 +1,210 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	db "github.com/zero-shubham/surveysvc/db/orm"
+)
+
+const (
+	// MaxAnswerTextLength caps answer_text for questions that do not set
+	// their own max_length.
+	MaxAnswerTextLength = 10000
+
+	// AnswerDateLayout is the format expected in answer_text for date
+	// questions.
+	AnswerDateLayout = "2006-01-02"
+
+	// MaxOptionLength is the longest option an answer can store, the width
+	// of the selected_option column.
+	MaxOptionLength = 255
+
+	defaultRatingMin = 1
+	defaultRatingMax = 5
+)
+
+// FieldError describes why a single field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when an answer does not satisfy the definition
+// of its question. It lists every offending field.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		msgs = append(msgs, field.Field+": "+field.Message)
+	}
+
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) add(field string, format string, args ...any) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (e *ValidationError) errOrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+// storableText reports whether Postgres accepts s in a text column, which
+// takes neither NUL bytes nor invalid UTF-8.
+func storableText(s string) bool {
+	return utf8.ValidString(s) && !strings.ContainsRune(s, 0)
+}
+
+// ValidateAnswer checks an answer against the definition of the question it
+// answers: options must be ones the question offers, with at most one for
+// single choice questions, numbers and ratings must fall within the
+// question's range, dates must parse, text must fit the question's
+// max_length and required questions must be answered. Text the database
+// would refuse to store is rejected here too, so that it fails the one
+// answer rather than the transaction it is written in.
+func ValidateAnswer(question db.Question, in AnswerInput) error {
+	var verr ValidationError
+	selected := in.Options()
+
+	if !storableText(in.AnswerText) {
+		verr.add("answer_text", "must be valid UTF-8 without NUL characters")
+	}
+
+	for _, option := range selected {
+		if !storableText(option) {
+			verr.add("selected_options", "options must be valid UTF-8 without NUL characters")
+		} else if utf8.RuneCountInString(option) > MaxOptionLength {
+			verr.add("selected_options", "options must be at most %d characters", MaxOptionLength)
+		}
+	}
+
+	if !storableText(in.IdempotencyKey) {
+		verr.add("idempotency_key", "must be valid UTF-8 without NUL characters")
+	}
+
+	if len(verr.Fields) > 0 {
+		return &verr
+	}
+
+	if len(selected) == 0 && in.AnswerText == "" {
+		if question.Required {
+			verr.add(answerField(question.QuestionType), "an answer is required")
+		}
+		return verr.errOrNil()
+	}
+
+	if HasOptions(question.QuestionType) {
+		if in.AnswerText != "" {
+			verr.add("answer_text", "not accepted for %s questions", question.QuestionType)
+		}
+
+		if len(selected) == 0 && question.Required {
+			verr.add(answerField(question.QuestionType), "an answer is required")
+		}
+
+		if question.QuestionType == db.QuestionTypeSingleChoice && len(selected) > 1 {
+			verr.add("selected_options", "only one option may be selected")
+		}
+
+		seen := make(map[string]struct{}, len(selected))
+		for _, option := range selected {
+			if _, ok := seen[option]; ok {
+				verr.add("selected_options", "%q is selected more than once", option)
+				continue
+			}
+			seen[option] = struct{}{}
+
+			if !slices.Contains(question.Options, option) {
+				verr.add("selected_options", "%q is not an option of this question", option)
+			}
+		}
+
+		return verr.errOrNil()
+	}
+
+	if in.SelectedOption != "" {
+		verr.add("selected_option", "not accepted for %s questions", question.QuestionType)
+	}
+
+	if len(in.SelectedOptions) > 0 {
+		verr.add("selected_options", "not accepted for %s questions", question.QuestionType)
+	}
+
+	if in.AnswerText == "" {
+		if question.Required {
+			verr.add("answer_text", "an answer is required")
+		}
+		return verr.errOrNil()
+	}
+
+	switch question.QuestionType {
+	case db.QuestionTypeFreeText:
+		maxLength := MaxAnswerTextLength
+		if question.MaxLength.Valid {
+			maxLength = int(question.MaxLength.Int32)
+		}
+
+		if utf8.RuneCountInString(in.AnswerText) > maxLength {
+			verr.add("answer_text", "must be at most %d characters", maxLength)
+		}
+
+	case db.QuestionTypeRating:
+		minValue, maxValue := float64(defaultRatingMin), float64(defaultRatingMax)
+		if question.MinValue.Valid {
+			minValue = question.MinValue.Float64
+		}
+		if question.MaxValue.Valid {
+			maxValue = question.MaxValue.Float64
+		}
+
+		rating, err := strconv.Atoi(strings.TrimSpace(in.AnswerText))
+		if err != nil {
+			verr.add("answer_text", "must be a whole number")
+		} else if float64(rating) < minValue || float64(rating) > maxValue {
+			verr.add("answer_text", "must be between %g and %g", minValue, maxValue)
+		}
+
+	case db.QuestionTypeNumeric:
+		value, err := strconv.ParseFloat(strings.TrimSpace(in.AnswerText), 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			verr.add("answer_text", "must be a number")
+		} else if question.MinValue.Valid && value < question.MinValue.Float64 {
+			verr.add("answer_text", "must be at least %g", question.MinValue.Float64)
+		} else if question.MaxValue.Valid && value > question.MaxValue.Float64 {
+			verr.add("answer_text", "must be at most %g", question.MaxValue.Float64)
+		}
+
+	case db.QuestionTypeDate:
+		if _, err := time.Parse(AnswerDateLayout, strings.TrimSpace(in.AnswerText)); err != nil {
+			verr.add("answer_text", "must be a date formatted as %s", AnswerDateLayout)
+		}
+	}
+
+	return verr.errOrNil()
+}
+
+// answerField is the field that carries the answer for a question type.
+func answerField(questionType db.QuestionType) string {
+	if questionType == db.QuestionTypeMultiChoice {
+		return "selected_options"
+	}
+
+	if questionType == db.QuestionTypeSingleChoice {
+		return "selected_option"
+	}
+
+	return "answer_text"
+}
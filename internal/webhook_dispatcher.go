@@ -0,0 +1,364 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+	"github.com/zero-shubham/surveysvc/transport/messaging"
+)
+
+// Headers sent with every webhook delivery.
+const (
+	WebhookSignatureHeader = "X-Surveysvc-Signature"
+	WebhookEventHeader     = "X-Surveysvc-Event"
+	WebhookDeliveryHeader  = "X-Surveysvc-Delivery"
+)
+
+const (
+	// webhookLease is how long a claimed delivery is hidden from other
+	// dispatchers, and so the longest a single attempt may take.
+	webhookLease = time.Minute
+	// webhookDrainLimit caps how much of a response is read, and thrown
+	// away, so that its connection can be reused.
+	webhookDrainLimit = 4 << 10
+)
+
+var (
+	// ErrWebhookAddressNotAllowed is returned when a webhook points at an
+	// address of the service's own network rather than at the internet.
+	ErrWebhookAddressNotAllowed = errors.New("webhook address is not allowed")
+
+	// ErrInvalidWebhookSignature is returned by VerifyWebhookSignature when a
+	// delivery was not signed with the secret, or not recently enough.
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+	// DefaultWebhookRetryPolicy spreads the attempts at a delivery over about
+	// four hours before giving up on it.
+	DefaultWebhookRetryPolicy = messaging.RetryPolicy{
+		MaxAttempts: 8,
+		BaseDelay:   30 * time.Second,
+		MaxDelay:    time.Hour,
+		Jitter:      0.2,
+	}
+)
+
+// WebhookPayload is the body of a webhook delivery. Data is the event as it is
+// published to Kafka.
+type WebhookPayload struct {
+	ID        string          `json:"id"`
+	EventType string          `json:"event_type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// SignWebhook returns the signature header of a delivery of body at ts, in
+// the form t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">.
+// The timestamp is signed along with the body so that receivers can reject
+// replayed deliveries.
+func SignWebhook(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + t + ",v1=" + hex.EncodeToString(webhookMAC(secret, t, body))
+}
+
+// VerifyWebhookSignature checks a signature header made by SignWebhook, and,
+// if tolerance is positive, that it was made no more than tolerance ago.
+func VerifyWebhookSignature(secret string, header string, body []byte, tolerance time.Duration) error {
+	var t string
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			if sig, err := hex.DecodeString(value); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return ErrInvalidWebhookSignature
+	}
+
+	if tolerance > 0 && time.Since(time.Unix(unix, 0)).Abs() > tolerance {
+		return ErrInvalidWebhookSignature
+	}
+
+	expected := webhookMAC(secret, t, body)
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			return nil
+		}
+	}
+
+	return ErrInvalidWebhookSignature
+}
+
+func webhookMAC(secret string, t string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// nonPublicPrefixes are ranges that are not reachable on the internet but are
+// not caught by the netip.Addr predicates used by publicAddress.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2002::/16"),
+}
+
+// publicAddress reports whether webhooks may be delivered to addr, which
+// they may unless it is loopback, private, link-local, multicast or otherwise
+// not on the internet. This keeps webhooks from reaching the service's own
+// network, cloud metadata endpoints included.
+func publicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// dialPublic refuses connections to addresses that are not public. It runs
+// once the host of a webhook was resolved, so it also covers hostnames that
+// resolve to the service's own network, whenever they do.
+func dialPublic(network string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrWebhookAddressNotAllowed, address)
+	}
+
+	if !publicAddress(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrWebhookAddressNotAllowed, addrPort.Addr())
+	}
+
+	return nil
+}
+
+// NewWebhookClient returns the client webhooks are delivered with. It only
+// connects to public addresses, does not go through proxies, which would
+// connect on its behalf, and does not follow redirects, which a response
+// could otherwise use to point the delivery elsewhere; a redirect is treated
+// as a failed delivery.
+func NewWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: dialPublic,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// WebhookDeliveryStore claims deliveries and records their outcome, as
+// *db.Queries does.
+type WebhookDeliveryStore interface {
+	ClaimWebhookDeliveries(ctx context.Context, arg db.ClaimWebhookDeliveriesParams) ([]db.ClaimWebhookDeliveriesRow, error)
+	MarkWebhookDeliverySucceeded(ctx context.Context, arg db.MarkWebhookDeliverySucceededParams) error
+	RecordWebhookDeliveryFailure(ctx context.Context, arg db.RecordWebhookDeliveryFailureParams) error
+}
+
+// WebhookDispatcher delivers the webhook deliveries that are due. A delivery
+// that is not answered with a 2xx status is retried following the retry
+// policy, and marked as failed once it runs out of attempts.
+type WebhookDispatcher struct {
+	logger    *zerolog.Logger
+	store     WebhookDeliveryStore
+	client    *http.Client
+	retry     messaging.RetryPolicy
+	batchSize int32
+}
+
+// NewWebhookDispatcher returns a dispatcher sending deliveries with client,
+// or, if client is nil, with a client from NewWebhookClient timing out after
+// 10 seconds.
+func NewWebhookDispatcher(logger *zerolog.Logger, store WebhookDeliveryStore, client *http.Client, retry messaging.RetryPolicy, batchSize int) *WebhookDispatcher {
+	if client == nil {
+		client = NewWebhookClient(10 * time.Second)
+	}
+
+	return &WebhookDispatcher{
+		logger:    logger,
+		store:     store,
+		client:    client,
+		retry:     retry,
+		batchSize: int32(batchSize),
+	}
+}
+
+// Run delivers due webhooks every interval until ctx is done, right away again
+// as long as there are more waiting.
+func (d *WebhookDispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		delivered, err := d.DeliverDue(ctx)
+		if err != nil {
+			d.logger.Err(err).Msg("failed to deliver webhooks")
+		}
+
+		if err == nil && delivered == int(d.batchSize) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue attempts one batch of due deliveries, concurrently, and returns
+// how many were attempted. Deliveries are leased when claimed, so several
+// dispatchers can run at once; a delivery whose outcome could not be recorded
+// is attempted again once its lease runs out.
+func (d *WebhookDispatcher) DeliverDue(ctx context.Context) (int, error) {
+	rows, err := d.store.ClaimWebhookDeliveries(ctx, db.ClaimWebhookDeliveriesParams{
+		MaxDeliveries: d.batchSize,
+		LeaseSeconds:  webhookLease.Seconds(),
+	})
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, row := range rows {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.deliver(ctx, row)
+		}()
+	}
+	wg.Wait()
+
+	return len(rows), nil
+}
+
+func (d *WebhookDispatcher) deliver(ctx context.Context, row db.ClaimWebhookDeliveriesRow) {
+	status, err := d.send(ctx, row)
+
+	logger := d.logger.With().
+		Str("delivery_id", row.ID.String()).
+		Str("event_type", row.EventType).
+		Int("status", status).
+		Logger()
+
+	responseStatus := pgtype.Int4{Int32: int32(status), Valid: status != 0}
+
+	if err == nil {
+		err = d.store.MarkWebhookDeliverySucceeded(ctx, db.MarkWebhookDeliverySucceededParams{
+			ID:             row.ID,
+			ResponseStatus: responseStatus,
+		})
+		if err != nil {
+			logger.Err(err).Msg("failed to record webhook delivery")
+		}
+		return
+	}
+
+	attempt := int(row.Attempts) + 1
+	params := db.RecordWebhookDeliveryFailureParams{
+		ID:             row.ID,
+		Status:         db.WebhookDeliveryStatusPending,
+		ResponseStatus: responseStatus,
+		LastError:      pgtype.Text{String: err.Error(), Valid: true},
+		NextAttemptAt:  pgtype.Timestamptz{Time: time.Now().Add(d.retry.Delay(attempt)), Valid: true},
+	}
+	if attempt >= max(d.retry.MaxAttempts, 1) {
+		params.Status = db.WebhookDeliveryStatusFailed
+		logger.Error().Err(err).Int("attempts", attempt).Msg("giving up on webhook delivery")
+	} else {
+		logger.Warn().Err(err).Int("attempts", attempt).Msg("webhook delivery failed")
+	}
+
+	if err := d.store.RecordWebhookDeliveryFailure(ctx, params); err != nil {
+		logger.Err(err).Msg("failed to record webhook delivery failure")
+	}
+}
+
+// send posts a delivery and returns the status it was answered with, and an
+// error unless the status is 2xx. The body of the response is not kept, since
+// the delivery log shows the error to whoever manages the webhook.
+func (d *WebhookDispatcher) send(ctx context.Context, row db.ClaimWebhookDeliveriesRow) (int, error) {
+	body, err := json.Marshal(WebhookPayload{
+		ID:        row.ID.String(),
+		EventType: row.EventType,
+		CreatedAt: row.CreatedAt.Time,
+		Data:      row.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookLease)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, row.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, row.EventType)
+	req.Header.Set(WebhookDeliveryHeader, row.ID.String())
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(row.Secret, time.Now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, webhookDrainLimit))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, nil
+	}
+
+	return resp.StatusCode, fmt.Errorf("webhook answered %s", resp.Status)
+}
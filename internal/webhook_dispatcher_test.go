@@ -0,0 +1,335 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+	"github.com/zero-shubham/surveysvc/transport/messaging"
+)
+
+const testWebhookSecret = "test-secret"
+
+// delivery is a row of the in-memory delivery log.
+type delivery struct {
+	row            db.ClaimWebhookDeliveriesRow
+	status         db.WebhookDeliveryStatus
+	responseStatus pgtype.Int4
+	lastError      pgtype.Text
+	nextAttemptAt  time.Time
+}
+
+// memoryDeliveries stands in for the webhook_deliveries table.
+type memoryDeliveries struct {
+	mu         sync.Mutex
+	deliveries []*delivery
+}
+
+func (m *memoryDeliveries) add(url string, payload string) *delivery {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d := &delivery{
+		row: db.ClaimWebhookDeliveriesRow{
+			ID:        uuid.New(),
+			EventType: AnswerCreatedEvent,
+			Payload:   []byte(payload),
+			CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+			Url:       url,
+			Secret:    testWebhookSecret,
+		},
+		status:        db.WebhookDeliveryStatusPending,
+		nextAttemptAt: time.Now(),
+	}
+	m.deliveries = append(m.deliveries, d)
+
+	return d
+}
+
+// get returns a copy of a delivery, so that it can be read while the
+// dispatcher is not running.
+func (m *memoryDeliveries) get(id uuid.UUID) delivery {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, d := range m.deliveries {
+		if d.row.ID == id {
+			return *d
+		}
+	}
+
+	return delivery{}
+}
+
+// makeDue lets a delivery be claimed again without waiting for its retry.
+func (m *memoryDeliveries) makeDue(id uuid.UUID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, d := range m.deliveries {
+		if d.row.ID == id {
+			d.nextAttemptAt = time.Now()
+		}
+	}
+}
+
+func (m *memoryDeliveries) ClaimWebhookDeliveries(_ context.Context, arg db.ClaimWebhookDeliveriesParams) ([]db.ClaimWebhookDeliveriesRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rows []db.ClaimWebhookDeliveriesRow
+	for _, d := range m.deliveries {
+		if len(rows) == int(arg.MaxDeliveries) {
+			break
+		}
+
+		if d.status != db.WebhookDeliveryStatusPending || d.nextAttemptAt.After(time.Now()) {
+			continue
+		}
+
+		d.nextAttemptAt = time.Now().Add(time.Duration(arg.LeaseSeconds * float64(time.Second)))
+		rows = append(rows, d.row)
+	}
+
+	return rows, nil
+}
+
+func (m *memoryDeliveries) MarkWebhookDeliverySucceeded(_ context.Context, arg db.MarkWebhookDeliverySucceededParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, d := range m.deliveries {
+		if d.row.ID == arg.ID {
+			d.status = db.WebhookDeliveryStatusSucceeded
+			d.row.Attempts++
+			d.responseStatus = arg.ResponseStatus
+			d.lastError = pgtype.Text{}
+		}
+	}
+
+	return nil
+}
+
+func (m *memoryDeliveries) RecordWebhookDeliveryFailure(_ context.Context, arg db.RecordWebhookDeliveryFailureParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, d := range m.deliveries {
+		if d.row.ID == arg.ID {
+			d.status = arg.Status
+			d.row.Attempts++
+			d.responseStatus = arg.ResponseStatus
+			d.lastError = arg.LastError
+			d.nextAttemptAt = arg.NextAttemptAt.Time
+		}
+	}
+
+	return nil
+}
+
+func newTestDispatcher(store WebhookDeliveryStore, client *http.Client, retry messaging.RetryPolicy) *WebhookDispatcher {
+	logger := zerolog.Nop()
+	return NewWebhookDispatcher(&logger, store, client, retry, 10)
+}
+
+func TestWebhookDispatcherSignsDeliveries(t *testing.T) {
+	type received struct {
+		header  http.Header
+		body    []byte
+		signErr error
+	}
+	requests := make(chan received, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{
+			header:  r.Header.Clone(),
+			body:    body,
+			signErr: VerifyWebhookSignature(testWebhookSecret, r.Header.Get(WebhookSignatureHeader), body, time.Minute),
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	store := &memoryDeliveries{}
+	d := store.add(srv.URL, `{"user_id":"4f7c6f4e-2a55-4b9f-9d2e-6f1c3a0b8e11"}`)
+
+	delivered, err := newTestDispatcher(store, srv.Client(), DefaultWebhookRetryPolicy).DeliverDue(context.Background())
+	if err != nil || delivered != 1 {
+		t.Fatalf("DeliverDue() = %d, %v, want 1, nil", delivered, err)
+	}
+
+	req := <-requests
+	if req.signErr != nil {
+		t.Errorf("signature of delivery: %v", req.signErr)
+	}
+	if err := VerifyWebhookSignature("other-secret", req.header.Get(WebhookSignatureHeader), req.body, time.Minute); !errors.Is(err, ErrInvalidWebhookSignature) {
+		t.Errorf("signature verified with another secret: %v", err)
+	}
+	if got := req.header.Get(WebhookEventHeader); got != AnswerCreatedEvent {
+		t.Errorf("%s = %q, want %q", WebhookEventHeader, got, AnswerCreatedEvent)
+	}
+	if got := req.header.Get(WebhookDeliveryHeader); got != d.row.ID.String() {
+		t.Errorf("%s = %q, want %q", WebhookDeliveryHeader, got, d.row.ID)
+	}
+
+	var payload WebhookPayload
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatalf("delivery body: %v", err)
+	}
+	if payload.ID != d.row.ID.String() || payload.EventType != AnswerCreatedEvent || string(payload.Data) != string(d.row.Payload) {
+		t.Errorf("delivery body = %s", req.body)
+	}
+
+	got := store.get(d.row.ID)
+	if got.status != db.WebhookDeliveryStatusSucceeded || got.row.Attempts != 1 || got.responseStatus.Int32 != http.StatusNoContent {
+		t.Errorf("delivery log = %s after %d attempts, status %d, want succeeded after 1, status 204", got.status, got.row.Attempts, got.responseStatus.Int32)
+	}
+}
+
+func TestWebhookDispatcherRetriesUntilFailed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "stack trace with internal details", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	retry := messaging.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour}
+	dispatcher := newTestDispatcher(&memoryDeliveries{}, srv.Client(), retry)
+	store := dispatcher.store.(*memoryDeliveries)
+	d := store.add(srv.URL, `{}`)
+
+	for attempt := 1; attempt <= retry.MaxAttempts; attempt++ {
+		before := time.Now()
+		if _, err := dispatcher.DeliverDue(context.Background()); err != nil {
+			t.Fatalf("DeliverDue() error = %v", err)
+		}
+
+		got := store.get(d.row.ID)
+		if int(got.row.Attempts) != attempt {
+			t.Fatalf("attempts = %d, want %d", got.row.Attempts, attempt)
+		}
+		if got.responseStatus.Int32 != http.StatusInternalServerError {
+			t.Errorf("response status = %d, want 500", got.responseStatus.Int32)
+		}
+		if got.lastError.String != "webhook answered 500 Internal Server Error" {
+			t.Errorf("last error = %q, want the status only", got.lastError.String)
+		}
+
+		if attempt < retry.MaxAttempts {
+			if got.status != db.WebhookDeliveryStatusPending {
+				t.Errorf("status after attempt %d = %s, want pending", attempt, got.status)
+			}
+			if wait := got.nextAttemptAt.Sub(before); wait < retry.Delay(attempt) {
+				t.Errorf("retried after %s, want at least %s", wait, retry.Delay(attempt))
+			}
+
+			// Nothing is due until the retry delay is over.
+			if n, _ := dispatcher.DeliverDue(context.Background()); n != 0 {
+				t.Errorf("DeliverDue() before the retry delay = %d, want 0", n)
+			}
+			store.makeDue(d.row.ID)
+			continue
+		}
+
+		if got.status != db.WebhookDeliveryStatusFailed {
+			t.Errorf("status after attempt %d = %s, want failed", attempt, got.status)
+		}
+	}
+
+	store.makeDue(d.row.ID)
+	if n, _ := dispatcher.DeliverDue(context.Background()); n != 0 {
+		t.Errorf("DeliverDue() after giving up = %d, want 0", n)
+	}
+}
+
+func TestWebhookDispatcherDoesNotFollowRedirects(t *testing.T) {
+	var redirected atomic.Bool
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected.Store(true)
+	}))
+	defer target.Close()
+
+	srv := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer srv.Close()
+
+	// Loopback servers are only reachable with the test server's dialer, so
+	// the redirect policy of the webhook client is taken over onto it.
+	client := srv.Client()
+	client.CheckRedirect = NewWebhookClient(time.Second).CheckRedirect
+
+	store := &memoryDeliveries{}
+	d := store.add(srv.URL, `{}`)
+
+	if _, err := newTestDispatcher(store, client, DefaultWebhookRetryPolicy).DeliverDue(context.Background()); err != nil {
+		t.Fatalf("DeliverDue() error = %v", err)
+	}
+
+	if redirected.Load() {
+		t.Error("delivery followed the redirect")
+	}
+
+	got := store.get(d.row.ID)
+	if got.status != db.WebhookDeliveryStatusPending || got.responseStatus.Int32 != http.StatusTemporaryRedirect {
+		t.Errorf("delivery log = %s, status %d, want pending, status 307", got.status, got.responseStatus.Int32)
+	}
+}
+
+func TestWebhookClientRefusesPrivateAddresses(t *testing.T) {
+	var called atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called.Store(true)
+	}))
+	defer srv.Close()
+
+	port := srv.URL[strings.LastIndex(srv.URL, ":"):]
+	for _, url := range []string{srv.URL, "http://localhost" + port} {
+		_, err := NewWebhookClient(time.Second).Post(url, "application/json", strings.NewReader(`{}`))
+		if !errors.Is(err, ErrWebhookAddressNotAllowed) {
+			t.Errorf("POST %s error = %v, want %v", url, err, ErrWebhookAddressNotAllowed)
+		}
+	}
+
+	if called.Load() {
+		t.Error("webhook client reached a loopback server")
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	tests := map[string]bool{
+		"8.8.8.8":            true,
+		"2606:4700::1111":    true,
+		"127.0.0.1":          false,
+		"::1":                false,
+		"10.0.0.1":           false,
+		"172.16.5.4":         false,
+		"192.168.1.1":        false,
+		"169.254.169.254":    false,
+		"fe80::1":            false,
+		"fd00::1":            false,
+		"100.64.0.1":         false,
+		"0.0.0.0":            false,
+		"224.0.0.1":          false,
+		"::ffff:127.0.0.1":   false,
+		"::ffff:169.254.1.1": false,
+		"64:ff9b::a00:1":     false,
+	}
+
+	for addr, want := range tests {
+		if got := publicAddress(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("publicAddress(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/netip"
+	"net/url"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	db "github.com/zero-shubham/surveysvc/db/orm"
+)
+
+const (
+	// MaxWebhookDeliveries caps how many deliveries are listed at once.
+	MaxWebhookDeliveries = 100
+
+	webhookSecretBytes = 32
+)
+
+// ErrWebhookNotFound is returned when a webhook that does not exist is looked
+// up, edited or deleted.
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// WebhookInput subscribes an org to events. An empty secret is replaced with
+// a generated one.
+type WebhookInput struct {
+	OrgID      uuid.UUID
+	URL        string
+	Secret     string
+	EventTypes []string
+}
+
+// WebhookChange replaces the editable settings of a webhook. Its secret and
+// org cannot be changed.
+type WebhookChange struct {
+	URL        string
+	EventTypes []string
+	Active     bool
+}
+
+// validateWebhook checks that a webhook calls an absolute http(s) URL and
+// subscribes to events that exist, returning its event types without
+// duplicates. URLs naming an address that is not public are rejected here;
+// hostnames are checked when deliveries connect to them.
+func validateWebhook(rawURL string, eventTypes []string) ([]string, error) {
+	var verr ValidationError
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		verr.add("url", "must be an absolute http or https URL")
+	} else if addr, err := netip.ParseAddr(u.Hostname()); err == nil && !publicAddress(addr) {
+		verr.add("url", "must not point at a private, loopback or link-local address")
+	}
+
+	if len(eventTypes) == 0 {
+		verr.add("event_types", "at least one event type is required")
+	}
+
+	var types []string
+	for _, eventType := range eventTypes {
+		if !slices.Contains(EventTypes, eventType) {
+			verr.add("event_types", "unknown event type %q", eventType)
+			continue
+		}
+
+		if !slices.Contains(types, eventType) {
+			types = append(types, eventType)
+		}
+	}
+
+	return types, verr.errOrNil()
+}
+
+// CreateWebhook subscribes an org to events.
+func (s *Service) CreateWebhook(ctx context.Context, in WebhookInput) (db.Webhook, error) {
+	eventTypes, err := validateWebhook(in.URL, in.EventTypes)
+	if err != nil {
+		return db.Webhook{}, err
+	}
+
+	secret := in.Secret
+	if secret == "" {
+		raw := make([]byte, webhookSecretBytes)
+		if _, err := rand.Read(raw); err != nil {
+			return db.Webhook{}, err
+		}
+		secret = hex.EncodeToString(raw)
+	}
+
+	return db.New(s.conn).CreateWebhook(ctx, db.CreateWebhookParams{
+		OrgID:      in.OrgID,
+		Url:        in.URL,
+		Secret:     secret,
+		EventTypes: eventTypes,
+	})
+}
+
+// GetWebhook fetches a webhook.
+func (s *Service) GetWebhook(ctx context.Context, webhookID uuid.UUID) (db.Webhook, error) {
+	webhook, err := db.New(s.conn).GetWebhookByID(ctx, webhookID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Webhook{}, ErrWebhookNotFound
+	}
+
+	return webhook, err
+}
+
+// ListWebhooks lists the webhooks of an org.
+func (s *Service) ListWebhooks(ctx context.Context, orgID uuid.UUID) ([]db.Webhook, error) {
+	return db.New(s.conn).GetWebhooksByOrgID(ctx, orgID)
+}
+
+// UpdateWebhook replaces the settings of a webhook. Deliveries already queued
+// still go to the URL it has when they are attempted.
+func (s *Service) UpdateWebhook(ctx context.Context, webhookID uuid.UUID, change WebhookChange) (db.Webhook, error) {
+	eventTypes, err := validateWebhook(change.URL, change.EventTypes)
+	if err != nil {
+		return db.Webhook{}, err
+	}
+
+	webhook, err := db.New(s.conn).UpdateWebhookByID(ctx, db.UpdateWebhookByIDParams{
+		ID:         webhookID,
+		Url:        change.URL,
+		EventTypes: eventTypes,
+		Active:     change.Active,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Webhook{}, ErrWebhookNotFound
+	}
+
+	return webhook, err
+}
+
+// DeleteWebhook removes a webhook along with its delivery log.
+func (s *Service) DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error {
+	deleted, err := db.New(s.conn).DeleteWebhookByID(ctx, webhookID)
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// WebhookDeliveries lists the latest deliveries of a webhook, newest first,
+// optionally only those with the given status.
+func (s *Service) WebhookDeliveries(ctx context.Context, webhookID uuid.UUID, status db.NullWebhookDeliveryStatus, limit int) ([]db.WebhookDelivery, error) {
+	orm := db.New(s.conn)
+
+	if _, err := orm.GetWebhookByID(ctx, webhookID); errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > MaxWebhookDeliveries {
+		limit = MaxWebhookDeliveries
+	}
+
+	return orm.GetWebhookDeliveries(ctx, db.GetWebhookDeliveriesParams{
+		WebhookID:     webhookID,
+		Status:        status,
+		MaxDeliveries: int32(limit),
+	})
+}
@@ -0,0 +1,129 @@
+package messaging
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Headers added to messages written to a dead letter topic, recording where
+// the message came from and why it could not be processed.
+const (
+	DeadLetterHeaderPrefix        = "dlq-"
+	DeadLetterTopicHeader         = DeadLetterHeaderPrefix + "original-topic"
+	DeadLetterPartitionHeader     = DeadLetterHeaderPrefix + "original-partition"
+	DeadLetterOffsetHeader        = DeadLetterHeaderPrefix + "original-offset"
+	DeadLetterErrorHeader         = DeadLetterHeaderPrefix + "error"
+	DeadLetterAttemptsHeader      = DeadLetterHeaderPrefix + "attempts"
+	DeadLetterFirstFailureHeader  = DeadLetterHeaderPrefix + "first-failure-at"
+	DeadLetterLastFailureHeader   = DeadLetterHeaderPrefix + "last-failure-at"
+	DeadLetterConsumerGroupHeader = DeadLetterHeaderPrefix + "consumer-group"
+	deadLetterTimeLayout          = time.RFC3339Nano
+)
+
+// DeadLetter describes why a message ended up on a dead letter topic.
+type DeadLetter struct {
+	Topic          string    `json:"original_topic"`
+	Partition      int       `json:"original_partition"`
+	Offset         int64     `json:"original_offset"`
+	Error          string    `json:"error"`
+	Attempts       int       `json:"attempts"`
+	FirstFailureAt time.Time `json:"first_failure_at"`
+	LastFailureAt  time.Time `json:"last_failure_at"`
+	ConsumerGroup  string    `json:"consumer_group"`
+}
+
+// failure keeps count of the failed attempts at processing a message.
+type failure struct {
+	err            error
+	attempts       int
+	firstFailureAt time.Time
+	lastFailureAt  time.Time
+}
+
+// record notes the outcome of an attempt and passes its error on.
+func (f *failure) record(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	now := time.Now()
+	if f.attempts == 0 {
+		f.firstFailureAt = now
+	}
+
+	f.err = err
+	f.attempts++
+	f.lastFailureAt = now
+
+	return err
+}
+
+// deadLetterMessage copies m for the dead letter topic, replacing any dead
+// letter headers it carried from an earlier failure with those of f.
+func deadLetterMessage(m *kafka.Message, f *failure, consumerGroup string) kafka.Message {
+	headers := WithoutDeadLetterHeaders(m.Headers)
+	headers = append(headers,
+		kafka.Header{Key: DeadLetterTopicHeader, Value: []byte(m.Topic)},
+		kafka.Header{Key: DeadLetterPartitionHeader, Value: []byte(strconv.Itoa(m.Partition))},
+		kafka.Header{Key: DeadLetterOffsetHeader, Value: []byte(strconv.FormatInt(m.Offset, 10))},
+		kafka.Header{Key: DeadLetterErrorHeader, Value: []byte(f.err.Error())},
+		kafka.Header{Key: DeadLetterAttemptsHeader, Value: []byte(strconv.Itoa(f.attempts))},
+		kafka.Header{Key: DeadLetterFirstFailureHeader, Value: []byte(f.firstFailureAt.UTC().Format(deadLetterTimeLayout))},
+		kafka.Header{Key: DeadLetterLastFailureHeader, Value: []byte(f.lastFailureAt.UTC().Format(deadLetterTimeLayout))},
+		kafka.Header{Key: DeadLetterConsumerGroupHeader, Value: []byte(consumerGroup)},
+	)
+
+	return kafka.Message{
+		Key:     m.Key,
+		Value:   m.Value,
+		Headers: headers,
+	}
+}
+
+// ParseDeadLetter reads the dead letter headers of a message, reporting false
+// if it has none.
+func ParseDeadLetter(m *kafka.Message) (DeadLetter, bool) {
+	var dl DeadLetter
+	found := false
+
+	for _, header := range m.Headers {
+		value := string(header.Value)
+
+		switch header.Key {
+		case DeadLetterTopicHeader:
+			dl.Topic = value
+			found = true
+		case DeadLetterPartitionHeader:
+			dl.Partition, _ = strconv.Atoi(value)
+		case DeadLetterOffsetHeader:
+			dl.Offset, _ = strconv.ParseInt(value, 10, 64)
+		case DeadLetterErrorHeader:
+			dl.Error = value
+		case DeadLetterAttemptsHeader:
+			dl.Attempts, _ = strconv.Atoi(value)
+		case DeadLetterFirstFailureHeader:
+			dl.FirstFailureAt, _ = time.Parse(deadLetterTimeLayout, value)
+		case DeadLetterLastFailureHeader:
+			dl.LastFailureAt, _ = time.Parse(deadLetterTimeLayout, value)
+		case DeadLetterConsumerGroupHeader:
+			dl.ConsumerGroup = value
+		}
+	}
+
+	return dl, found
+}
+
+// WithoutDeadLetterHeaders returns headers without the dead letter headers.
+func WithoutDeadLetterHeaders(headers []kafka.Header) []kafka.Header {
+	kept := make([]kafka.Header, 0, len(headers))
+	for _, header := range headers {
+		if !strings.HasPrefix(header.Key, DeadLetterHeaderPrefix) {
+			kept = append(kept, header)
+		}
+	}
+
+	return kept
+}
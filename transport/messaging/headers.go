@@ -0,0 +1,85 @@
+package messaging
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanHeaderAttributePrefix namespaces the span attributes that message
+// headers are recorded as.
+const spanHeaderAttributePrefix = "messaging.kafka.header."
+
+// DefaultSpanHeaders are the message headers recorded on consumer spans unless
+// WithSpanHeaders says otherwise.
+var DefaultSpanHeaders = []string{"content-type", EventTypeHeader}
+
+// HeaderCarrier lets a propagator read and write the headers of a Kafka
+// message, so that trace context and baggage travel along with it.
+type HeaderCarrier struct {
+	headers *[]kafka.Header
+}
+
+var _ propagation.TextMapCarrier = HeaderCarrier{}
+
+// NewHeaderCarrier returns a carrier over the headers of m.
+func NewHeaderCarrier(m *kafka.Message) HeaderCarrier {
+	return HeaderCarrier{headers: &m.Headers}
+}
+
+func (c HeaderCarrier) Get(key string) string {
+	for _, header := range *c.headers {
+		if header.Key == key {
+			return string(header.Value)
+		}
+	}
+
+	return ""
+}
+
+func (c HeaderCarrier) Set(key string, value string) {
+	for i, header := range *c.headers {
+		if header.Key == key {
+			(*c.headers)[i].Value = []byte(value)
+			return
+		}
+	}
+
+	*c.headers = append(*c.headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c HeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.headers))
+	for _, header := range *c.headers {
+		keys = append(keys, header.Key)
+	}
+
+	return keys
+}
+
+// messageContext returns ctx carrying the trace context and baggage the
+// producer of m put in its headers, if any.
+func (kh *KafkaConsumer) messageContext(ctx context.Context, m *kafka.Message) context.Context {
+	return kh.propagator.Extract(ctx, NewHeaderCarrier(m))
+}
+
+// messageLink links to the span that produced m, for spans that cover more
+// than one message or that start before m is known.
+func (kh *KafkaConsumer) messageLink(m *kafka.Message) trace.Link {
+	return trace.LinkFromContext(kh.messageContext(context.Background(), m))
+}
+
+// headerAttributes returns the allowed headers of m as span attributes.
+func (kh *KafkaConsumer) headerAttributes(m *kafka.Message) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(kh.spanHeaders))
+	for _, header := range m.Headers {
+		if _, ok := kh.spanHeaders[header.Key]; ok {
+			attrs = append(attrs, attribute.String(spanHeaderAttributePrefix+header.Key, string(header.Value)))
+		}
+	}
+
+	return attrs
+}
@@ -2,15 +2,19 @@ package messaging
 
 import (
 	"context"
+	"hash/fnv"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/segmentio/kafka-go"
 	"github.com/zero-shubham/surveysvc/config"
 	"github.com/zero-shubham/surveysvc/transport/tcp"
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -18,14 +22,105 @@ import (
 const PodNameEnv = "POD_NAME"
 
 type HandlerFunc func(context.Context, *kafka.Message) error
+
+// BatchHandlerFunc processes a batch of messages and returns one error per
+// message, nil for each message it processed.
+type BatchHandlerFunc func(context.Context, []*kafka.Message) []error
+
 type KafkaConsumer struct {
-	reader      *kafka.Reader
-	handler     HandlerFunc
-	deadletter  *kafka.Writer
-	logger      *zerolog.Logger
-	topic       string
-	messageChan chan *kafka.Message
-	trace       trace.Tracer
+	reader       *kafka.Reader
+	fallback     *handlerRoute
+	routes       map[string]*handlerRoute
+	batchHandler BatchHandlerFunc
+	batchSize    int
+	batchWait    time.Duration
+	routeByKey   bool
+	retry        RetryPolicy
+	groupID      string
+	logger       *zerolog.Logger
+	topic        string
+	workerChans  []chan *kafka.Message
+	offsets      *offsetTracker
+	metrics      *consumerMetrics
+	drainTimeout time.Duration
+	workers      sync.WaitGroup
+	done         chan struct{}
+	trace        trace.Tracer
+	propagator   propagation.TextMapPropagator
+	spanHeaders  map[string]struct{}
+}
+
+// DefaultDrainTimeout is how long a consumer that is shutting down waits for
+// in-flight messages unless configured otherwise.
+const DefaultDrainTimeout = 30 * time.Second
+
+// ConsumerOption configures optional behaviour of a KafkaConsumer.
+type ConsumerOption func(*KafkaConsumer)
+
+// WithBatching makes workers hand messages to handler in batches of up to
+// size messages, waiting at most wait for a batch to fill up. Messages the
+// batch handler fails on are retried one at a time before going to the dead
+// letter topic, so a poison message does not hold back the rest of its batch.
+func WithBatching(handler BatchHandlerFunc, size int, wait time.Duration) ConsumerOption {
+	return func(kh *KafkaConsumer) {
+		kh.batchHandler = handler
+		kh.batchSize = size
+		kh.batchWait = wait
+	}
+}
+
+// WithRetryPolicy sets how handlers are retried before a message goes to the
+// dead letter topic.
+func WithRetryPolicy(policy RetryPolicy) ConsumerOption {
+	return func(kh *KafkaConsumer) {
+		kh.retry = policy
+	}
+}
+
+// WithDrainTimeout sets how long the consumer waits, once its context is done,
+// for workers to finish the messages already fetched. Messages still being
+// processed when it runs out are abandoned without being committed, to be
+// redelivered to whichever consumer picks up their partition.
+func WithDrainTimeout(timeout time.Duration) ConsumerOption {
+	return func(kh *KafkaConsumer) {
+		kh.drainTimeout = timeout
+	}
+}
+
+// WithPropagator sets the propagator that reads the producer's trace context
+// and baggage from message headers, in place of the global one.
+func WithPropagator(propagator propagation.TextMapPropagator) ConsumerOption {
+	return func(kh *KafkaConsumer) {
+		kh.propagator = propagator
+	}
+}
+
+// WithSpanHeaders sets which message headers are recorded on consumer spans,
+// in place of DefaultSpanHeaders. Headers can carry anything a producer puts
+// in them, so only those named are recorded.
+func WithSpanHeaders(keys ...string) ConsumerOption {
+	return func(kh *KafkaConsumer) {
+		kh.spanHeaders = headerSet(keys)
+	}
+}
+
+func headerSet(keys []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		set[key] = struct{}{}
+	}
+
+	return set
+}
+
+// WithKeyRouting routes messages to workers by message key instead of by
+// partition, so that messages sharing a key keep their order while the keys
+// of a busy partition are spread over several workers. Messages without a key
+// are still routed by partition.
+func WithKeyRouting() ConsumerOption {
+	return func(kh *KafkaConsumer) {
+		kh.routeByKey = true
+	}
 }
 
 func NewKafkaConsumer(
@@ -36,6 +131,7 @@ func NewKafkaConsumer(
 	deadletterTopic string,
 	logger *zerolog.Logger,
 	tp *sdktrace.TracerProvider,
+	opts ...ConsumerOption,
 ) *KafkaConsumer {
 	logger.Info().Str("consumer_group", consumerGroupID).Msg("instantiating new consumer")
 
@@ -43,7 +139,7 @@ func NewKafkaConsumer(
 
 	dialer := tcp.NewInstrumentedDialer(time.Second*30, time.Minute*60, tracer, logger)
 
-	return &KafkaConsumer{
+	kh := &KafkaConsumer{
 		reader: kafka.NewReader(kafka.ReaderConfig{
 			Brokers:  brokers,
 			Topic:    topic,
@@ -51,45 +147,106 @@ func NewKafkaConsumer(
 			MaxBytes: 10e6,
 			Dialer:   dialer.Dialer,
 		}),
-		handler: handler,
-		deadletter: kafka.NewWriter(kafka.WriterConfig{
+		fallback: &handlerRoute{
+			name:            defaultHandlerName,
+			handler:         handler,
+			deadletterTopic: deadletterTopic,
+			deadletter: kafka.NewWriter(kafka.WriterConfig{
+				Brokers:  brokers,
+				Topic:    deadletterTopic,
+				Balancer: &kafka.LeastBytes{},
+				Dialer:   dialer.Dialer,
+			}),
+		},
+		routes:       make(map[string]*handlerRoute),
+		groupID:      consumerGroupID,
+		logger:       logger,
+		topic:        topic,
+		retry:        DefaultRetryPolicy,
+		offsets:      newOffsetTracker(),
+		drainTimeout: DefaultDrainTimeout,
+		done:         make(chan struct{}),
+		trace:        tracer,
+		propagator:   otel.GetTextMapPropagator(),
+		spanHeaders:  headerSet(DefaultSpanHeaders),
+	}
+
+	for _, opt := range opts {
+		opt(kh)
+	}
+
+	if kh.fallback.handler == nil {
+		kh.fallback.handler = unroutable
+	}
+
+	for _, route := range kh.routes {
+		if route.deadletterTopic == "" {
+			route.deadletter = kh.fallback.deadletter
+			continue
+		}
+
+		route.deadletter = kafka.NewWriter(kafka.WriterConfig{
 			Brokers:  brokers,
-			Topic:    deadletterTopic,
+			Topic:    route.deadletterTopic,
 			Balancer: &kafka.LeastBytes{},
 			Dialer:   dialer.Dialer,
-		}),
-		logger: logger,
-		topic:  topic,
-		trace:  tracer,
+		})
 	}
 
+	return kh
 }
 
+// Start fetches messages and hands them to workerCount workers until ctx is
+// done, after which the consumer drains: it stops fetching, lets the workers
+// finish the messages already fetched within the drain timeout, commits what
+// they completed and closes its reader and dead letter writer. Done is closed
+// once that is over.
 func (kh *KafkaConsumer) Start(ctx context.Context, workerCount int, mp metric.MeterProvider) {
 
 	meter := mp.Meter(config.ServiceName + "-consumer-" + os.Getenv(PodNameEnv))
 
-	kh.messageChan = make(chan *kafka.Message, workerCount)
-	for i := 0; i < workerCount; i++ {
-		go kh.workerStart(ctx, i)
+	var err error
+	kh.metrics, err = newConsumerMetrics(meter, kh.topic, kh.groupID, workerCount)
+	if err != nil {
+		kh.logger.Fatal().Err(err).Msg("failed to instantiate consumer metrics")
 	}
 
-	msgCounter, err := meter.Int64Counter(
-		"message_counter",
-		metric.WithDescription("Counts the  total messages read"),
-		metric.WithUnit("1"),
-	)
-	if err != nil {
-		kh.logger.Fatal().Err(err).Msg("failed to instantiate msg counter")
+	kh.fallback.metrics = kh.metrics.forHandler(kh.fallback.name)
+	for _, route := range kh.routes {
+		route.metrics = kh.metrics.forHandler(route.name)
+	}
+
+	// Workers outlive ctx while draining, and are only cancelled if the drain
+	// timeout runs out.
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+
+	// Each worker gets its own channel so that every message of a partition,
+	// or of a key, is handled by the same worker in the order it was fetched.
+	kh.workerChans = make([]chan *kafka.Message, workerCount)
+	for i := range kh.workerChans {
+		kh.workerChans[i] = make(chan *kafka.Message, max(kh.batchSize, 1))
+
+		kh.workers.Add(1)
+		if kh.batchHandler != nil {
+			go kh.batchWorkerStart(workCtx, i, kh.workerChans[i])
+		} else {
+			go kh.workerStart(workCtx, i, kh.workerChans[i])
+		}
 	}
 
 	go func() {
 		kh.logger.Info().Str("topic", kh.topic).Msg("starting consumer")
+
+		// Fetches that keep failing, say while the brokers are unreachable,
+		// are spaced out like the retries of a handler.
+		fetchFailures := 0
 		for {
 			select {
 			case <-ctx.Done():
 				kh.logger.Info().Str("topic", kh.topic).Msg("stopping kafka handler")
+				kh.drain(cancelWork)
 				kh.Stop()
+				close(kh.done)
 				return
 
 			default:
@@ -98,36 +255,117 @@ func (kh *KafkaConsumer) Start(ctx context.Context, workerCount int, mp metric.M
 
 				m, err := kh.reader.FetchMessage(ctx)
 				if err != nil {
-					kh.logger.Err(err).Msg("failed to fetch message")
+					span.End()
+					if ctx.Err() != nil {
+						continue
+					}
+
+					fetchFailures++
+					kh.logger.Err(err).Int("failures", fetchFailures).Msg("failed to fetch message")
+
+					timer := time.NewTimer(kh.retry.Delay(fetchFailures))
+					select {
+					case <-ctx.Done():
+						timer.Stop()
+					case <-timer.C:
+					}
 					continue
 				}
+				fetchFailures = 0
 
 				kh.logger.Info().Msg("fetched message")
 
-				otelAttrs := make([]attribute.KeyValue, len(m.Headers))
-				for _, header := range m.Headers {
-					otelAttrs = append(otelAttrs, attribute.KeyValue{
-						Key:   attribute.Key(header.Key),
-						Value: attribute.StringValue(string(header.Value)),
-					})
+				// Messages carry no generation, so a rebalance is noticed from
+				// the reader's stats. Offsets pending from before it are stale:
+				// the partition may be back at a later offset, and its gap
+				// would never be completed. So is the lag of partitions that
+				// were revoked.
+				if kh.reader.Stats().Rebalances > 0 {
+					kh.offsets.reset()
+					kh.metrics.resetLag()
 				}
-				msgCounter.Add(ctx, 1, metric.WithAttributes(attribute.Float64("timestamp", float64(time.Now().Unix()))))
 
-				span.SetAttributes(otelAttrs...)
+				kh.metrics.observeFetch(ctx, &m)
+
+				// The fetch starts before the message is known, so it links to
+				// the producer's span rather than continuing its trace.
+				span.AddLink(kh.messageLink(&m))
+				span.SetAttributes(kh.headerAttributes(&m)...)
 
-				kh.messageChan <- &m
+				// A message that is not handed over before ctx is done is left
+				// uncommitted and gets redelivered.
+				kh.offsets.track(&m)
+				select {
+				case kh.workerChans[kh.route(&m)] <- &m:
+				case <-ctx.Done():
+				}
 				span.End()
 			}
 		}
 	}()
 }
 
+// route picks the worker a message is handled by.
+func (kh *KafkaConsumer) route(m *kafka.Message) int {
+	if kh.routeByKey && len(m.Key) > 0 {
+		h := fnv.New32a()
+		h.Write(m.Key)
+		return int(h.Sum32() % uint32(len(kh.workerChans)))
+	}
+
+	return m.Partition % len(kh.workerChans)
+}
+
+// drain lets the workers finish the messages handed to them, cancelling them
+// if they do not within the drain timeout.
+func (kh *KafkaConsumer) drain(cancelWork context.CancelFunc) {
+	defer cancelWork()
+
+	for _, messages := range kh.workerChans {
+		close(messages)
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		kh.workers.Wait()
+		close(drained)
+	}()
+
+	timer := time.NewTimer(kh.drainTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-drained:
+		kh.logger.Info().Str("topic", kh.topic).Msg("drained in-flight messages")
+	case <-timer.C:
+		kh.logger.Warn().Str("topic", kh.topic).Dur("drain_timeout", kh.drainTimeout).Msg("drain timed out, abandoning in-flight messages")
+		cancelWork()
+		<-drained
+	}
+}
+
+// Done is closed once the consumer has drained and shut down after the
+// context passed to Start is done.
+func (kh *KafkaConsumer) Done() <-chan struct{} {
+	return kh.done
+}
+
 func (kh *KafkaConsumer) Stop() {
-	err := kh.deadletter.Close()
+	err := kh.fallback.deadletter.Close()
 	if err != nil {
 		kh.logger.Err(err).Msg("failed to close dead letter")
 	}
 
+	for _, route := range kh.routes {
+		if route.deadletter == kh.fallback.deadletter {
+			continue
+		}
+
+		if err := route.deadletter.Close(); err != nil {
+			kh.logger.Err(err).Str("event_type", route.name).Msg("failed to close dead letter")
+		}
+	}
+
 	err = kh.reader.Close()
 	if err != nil {
 		kh.logger.Err(err).Msg("failed to close reader")
@@ -135,64 +373,206 @@ func (kh *KafkaConsumer) Stop() {
 	kh.logger.Info().Msg("stopped kafka handler")
 }
 
-func (kh *KafkaConsumer) workerStart(ctx context.Context, id int) {
+func (kh *KafkaConsumer) workerStart(ctx context.Context, id int, messages <-chan *kafka.Message) {
+	defer kh.workers.Done()
+
 	kh.logger.Info().Msg("starting consumer worker")
+	for m := range messages {
+		kh.logger.Info().Msgf("message received on worker %d", id)
+		kh.process(ctx, kh.routeFor(m), m)
+	}
+
+	kh.logger.Info().Msg("shutting down consumer worker")
+}
+
+// process hands a message to the handler of its route, retrying it or dead
+// lettering it as needed, and commits it.
+func (kh *KafkaConsumer) process(ctx context.Context, route *handlerRoute, m *kafka.Message) {
+	ctx, span := kh.trace.Start(kh.messageContext(ctx, m), "process-answer",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(kh.headerAttributes(m)...),
+		trace.WithAttributes(attribute.String("handler", route.name)),
+	)
+	defer span.End()
+
+	done := route.metrics.startProcessing()
+
+	var f failure
+	err := kh.retry.Do(ctx, func() error {
+		err := f.record(route.handler(ctx, m))
+		if err != nil && !IsPermanent(err) {
+			kh.logger.Err(err).Msg("failure from message handler, retrying..")
+		}
+		return err
+	})
+	done(ctx)
+	if ctx.Err() != nil {
+		kh.logger.Warn().Int64("offset", m.Offset).Msg("abandoned message at shutdown")
+		return
+	}
+
+	if err != nil {
+		kh.logger.Err(err).Msg("failed to process message")
+		if !kh.deadLetter(ctx, route, m, &f) {
+			return
+		}
+	}
+	route.metrics.observeOutcome(ctx, &f, err != nil)
+
+	kh.commit(ctx, m)
+}
+
+// batchWorkerStart collects messages into batches, handing a batch over once
+// it is full or once batchWait has passed since its first message arrived.
+func (kh *KafkaConsumer) batchWorkerStart(ctx context.Context, id int, messages <-chan *kafka.Message) {
+	defer kh.workers.Done()
+
+	kh.logger.Info().Int("batch_size", kh.batchSize).Dur("batch_wait", kh.batchWait).Msg("starting batching consumer worker")
+
+	batch := make([]*kafka.Message, 0, kh.batchSize)
+	timer := time.NewTimer(kh.batchWait)
+	timer.Stop()
+
 	for {
 		select {
-		case <-ctx.Done():
-			kh.logger.Info().Msg("shutting down consumer worker")
-			return
+		case m, ok := <-messages:
+			if !ok {
+				if len(batch) > 0 {
+					kh.processBatch(ctx, batch)
+				}
+				kh.logger.Info().Msg("shutting down consumer worker")
+				return
+			}
 
-		case m := <-kh.messageChan:
-			ctx, span := kh.trace.Start(ctx, "process-answer")
-			otelAttrs := make([]attribute.KeyValue, len(m.Headers))
-			for _, header := range m.Headers {
-				otelAttrs = append(otelAttrs, attribute.KeyValue{
-					Key:   attribute.Key(header.Key),
-					Value: attribute.StringValue(string(header.Value)),
-				})
+			if len(batch) == 0 {
+				timer.Reset(kh.batchWait)
 			}
-			span.SetAttributes(otelAttrs...)
 
-			kh.logger.Info().Msgf("message received on worker %d", id)
+			batch = append(batch, m)
+			if len(batch) < kh.batchSize {
+				continue
+			}
 
-			err := ExponentialRetry(3, func() error {
-				err := kh.handler(ctx, m)
-				if err != nil {
-					kh.logger.Err(err).Msg("failure from message handler, retrying..")
+		case <-timer.C:
+		}
+
+		timer.Stop()
+		kh.logger.Info().Int("messages", len(batch)).Msgf("batch ready on worker %d", id)
+		kh.processBatch(ctx, batch)
+		batch = make([]*kafka.Message, 0, kh.batchSize)
+	}
+}
+
+// processBatch hands a batch to the batch handler and commits it once every
+// message was either processed or written to the dead letter topic. Messages
+// of event types with a handler of their own are processed one at a time
+// first.
+func (kh *KafkaConsumer) processBatch(ctx context.Context, messages []*kafka.Message) {
+	batch := messages[:0:0]
+	for _, m := range messages {
+		if route := kh.routeFor(m); route != kh.fallback {
+			kh.process(ctx, route, m)
+			continue
+		}
+
+		batch = append(batch, m)
+	}
+
+	if len(batch) == 0 {
+		return
+	}
+
+	// A batch has as many producers as it has messages, so its span links to
+	// each of them instead of picking one as its parent.
+	links := make([]trace.Link, 0, len(batch))
+	for _, m := range batch {
+		links = append(links, kh.messageLink(m))
+	}
+
+	ctx, span := kh.trace.Start(ctx, "process-answer-batch",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithLinks(links...),
+	)
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("messages", len(batch)))
+
+	done := kh.fallback.metrics.startProcessing()
+
+	errs := kh.batchHandler(ctx, batch)
+	failures := make([]failure, len(batch))
+	for i, m := range batch {
+		if errs[i] == nil {
+			continue
+		}
+
+		f := &failures[i]
+		err := f.record(errs[i])
+		if !IsPermanent(err) {
+			kh.logger.Err(err).Int64("offset", m.Offset).Msg("failure from batch handler, retrying message on its own..")
+			err = kh.retry.Do(ctx, func() error {
+				err := f.record(kh.batchHandler(ctx, []*kafka.Message{m})[0])
+				if err != nil && !IsPermanent(err) {
+					kh.logger.Err(err).Msg("failure from batch handler, retrying..")
 				}
 				return err
 			})
-			if err != nil {
-				kh.logger.Err(err).Msg("failed to process message")
-				ctx, span := kh.trace.Start(ctx, "dead-answer")
+		}
+		errs[i] = err
+	}
+	done(ctx)
 
-				err = kh.deadletter.WriteMessages(ctx, *m)
-				if err != nil {
-					kh.logger.Err(err).Msg("error while wrtiging to dead leader")
-				}
+	if ctx.Err() != nil {
+		kh.logger.Warn().Int("messages", len(batch)).Msg("abandoned batch at shutdown")
+		return
+	}
 
-				span.End()
+	completed := batch[:0:0]
+	for i, m := range batch {
+		if errs[i] != nil {
+			kh.logger.Err(errs[i]).Msg("failed to process message")
+			if !kh.deadLetter(ctx, kh.fallback, m, &failures[i]) {
+				continue
 			}
+		}
+		kh.fallback.metrics.observeOutcome(ctx, &failures[i], errs[i] != nil)
+		completed = append(completed, m)
+	}
 
-			ctx, commitSpan := kh.trace.Start(ctx, "commit-answer")
-			if err := kh.reader.CommitMessages(ctx, *m); err != nil {
-				kh.logger.Err(err).Msg("failed to commit messages")
-			}
+	kh.commit(ctx, completed...)
+}
 
-			commitSpan.End()
-			span.End()
-		}
+// deadLetter writes a message that could not be processed to the dead letter
+// topic of its route along with why it failed, and reports whether it did. A
+// message that could not be written is left uncommitted, which holds back the
+// commits of its partition until it is redelivered, after a restart or a
+// rebalance, rather than losing it.
+func (kh *KafkaConsumer) deadLetter(ctx context.Context, route *handlerRoute, m *kafka.Message, f *failure) bool {
+	ctx, span := kh.trace.Start(ctx, "dead-answer")
+	defer span.End()
+
+	err := route.deadletter.WriteMessages(ctx, deadLetterMessage(m, f, kh.groupID))
+	if err != nil {
+		kh.logger.Err(err).Int64("offset", m.Offset).Msg("error while wrtiging to dead leader, leaving message uncommitted")
+		return false
 	}
 
+	return true
 }
 
-func ExponentialRetry(maxRetry int, execute func() error) error {
-	counter := 1
-	var err error
-	for err = execute(); err != nil && counter < maxRetry; {
-		time.Sleep(time.Second * (time.Duration(counter + (2 + counter))))
-		counter++
+// commit marks messages as done and commits, per partition, the offset up to
+// which every message is done. Offsets behind a message another worker is
+// still busy with are left to be committed by that worker.
+func (kh *KafkaConsumer) commit(ctx context.Context, messages ...*kafka.Message) {
+	msgs := kh.offsets.complete(messages...)
+	if len(msgs) == 0 {
+		return
+	}
+
+	ctx, span := kh.trace.Start(ctx, "commit-answer")
+	defer span.End()
+
+	if err := kh.reader.CommitMessages(ctx, msgs...); err != nil {
+		kh.logger.Err(err).Msg("failed to commit messages")
 	}
-	return err
 }
@@ -0,0 +1,197 @@
+package messaging
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// consumerMetrics are the instruments a KafkaConsumer reports to. Everything
+// is labelled with the topic and consumer group, the lag additionally with
+// the partition and the outcomes with the handler, so that none of them grows
+// with the traffic.
+type consumerMetrics struct {
+	labels       []attribute.KeyValue
+	attrs        metric.MeasurementOption
+	fetched      metric.Int64Counter
+	succeeded    metric.Int64Counter
+	retried      metric.Int64Counter
+	deadLettered metric.Int64Counter
+	duration     metric.Float64Histogram
+
+	workers     int
+	busyWorkers atomic.Int64
+
+	mu  sync.Mutex
+	lag map[int]int64
+}
+
+func newConsumerMetrics(meter metric.Meter, topic string, consumerGroup string, workers int) (*consumerMetrics, error) {
+	labels := []attribute.KeyValue{
+		attribute.String("topic", topic),
+		attribute.String("consumer_group", consumerGroup),
+	}
+
+	cm := &consumerMetrics{
+		labels:  labels,
+		attrs:   metric.WithAttributes(labels...),
+		workers: workers,
+		lag:     make(map[int]int64),
+	}
+
+	var err error
+	cm.fetched, err = meter.Int64Counter(
+		"message_counter",
+		metric.WithDescription("Counts the  total messages read"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	cm.succeeded, err = meter.Int64Counter(
+		"messages_succeeded",
+		metric.WithDescription("Counts the messages processed successfully"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	cm.retried, err = meter.Int64Counter(
+		"messages_retried",
+		metric.WithDescription("Counts the messages that needed more than one attempt"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	cm.deadLettered, err = meter.Int64Counter(
+		"messages_dead_lettered",
+		metric.WithDescription("Counts the messages written to the dead letter topic"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	cm.duration, err = meter.Float64Histogram(
+		"message_processing_duration",
+		metric.WithDescription("Time taken to process a message, or a batch of them, including retries"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	lag, err := meter.Int64ObservableGauge(
+		"consumer_lag",
+		metric.WithDescription("Messages on a partition behind the last one fetched"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	utilization, err := meter.Float64ObservableGauge(
+		"worker_utilization",
+		metric.WithDescription("Fraction of the workers busy processing messages"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		cm.mu.Lock()
+		for partition, behind := range cm.lag {
+			o.ObserveInt64(lag, behind, metric.WithAttributes(append(labels, attribute.Int("partition", partition))...))
+		}
+		cm.mu.Unlock()
+
+		if cm.workers > 0 {
+			o.ObserveFloat64(utilization, float64(cm.busyWorkers.Load())/float64(cm.workers), cm.attrs)
+		}
+
+		return nil
+	}, lag, utilization)
+	if err != nil {
+		return nil, err
+	}
+
+	return cm, nil
+}
+
+// observeFetch counts a fetched message and notes how far behind the end of
+// its partition it is. The reader's own stats keep a single lag for all the
+// partitions a consumer group member is assigned, so the lag is taken from
+// the high water mark each fetched message carries instead. That leaves the
+// lag of a partition as it was when its last message was fetched: messages
+// produced since are only accounted for once the next one is fetched.
+func (cm *consumerMetrics) observeFetch(ctx context.Context, m *kafka.Message) {
+	cm.fetched.Add(ctx, 1, cm.attrs)
+
+	cm.mu.Lock()
+	cm.lag[m.Partition] = max(m.HighWaterMark-m.Offset-1, 0)
+	cm.mu.Unlock()
+}
+
+// resetLag forgets the lag of every partition, for when the consumer group
+// rebalanced and the partitions may have been handed to other members. The
+// partitions that stay are reported again as soon as a message is fetched
+// from them.
+func (cm *consumerMetrics) resetLag() {
+	cm.mu.Lock()
+	clear(cm.lag)
+	cm.mu.Unlock()
+}
+
+// handlerMetrics report the processing of the messages of one handler.
+type handlerMetrics struct {
+	*consumerMetrics
+	attrs metric.MeasurementOption
+}
+
+// forHandler returns the metrics of the handler of an event type, named
+// default for the consumer's own handler.
+func (cm *consumerMetrics) forHandler(name string) *handlerMetrics {
+	labels := append(append([]attribute.KeyValue{}, cm.labels...), attribute.String("handler", name))
+
+	return &handlerMetrics{
+		consumerMetrics: cm,
+		attrs:           metric.WithAttributes(labels...),
+	}
+}
+
+// startProcessing marks a worker as busy and returns a function that records
+// how long it took, to be called once the worker is done.
+func (hm *handlerMetrics) startProcessing() func(ctx context.Context) {
+	hm.busyWorkers.Add(1)
+	started := time.Now()
+
+	return func(ctx context.Context) {
+		hm.duration.Record(ctx, time.Since(started).Seconds(), hm.attrs)
+		hm.busyWorkers.Add(-1)
+	}
+}
+
+// observeOutcome counts a message that was processed, and whether it took
+// more than one attempt, or that went to the dead letter topic.
+func (hm *handlerMetrics) observeOutcome(ctx context.Context, f *failure, deadLettered bool) {
+	// Every failure but the last of a dead lettered message led to a retry.
+	if f.attempts > 1 || (f.attempts == 1 && !deadLettered) {
+		hm.retried.Add(ctx, 1, hm.attrs)
+	}
+
+	if deadLettered {
+		hm.deadLettered.Add(ctx, 1, hm.attrs)
+	} else {
+		hm.succeeded.Add(ctx, 1, hm.attrs)
+	}
+}
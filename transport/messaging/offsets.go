@@ -0,0 +1,100 @@
+package messaging
+
+import (
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// offsetTracker keeps track of the messages fetched from each partition that
+// are still being processed, so that an offset is committed only once every
+// message before it on its partition is done.
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[int]*partitionOffsets
+}
+
+type partitionOffsets struct {
+	topic   string
+	pending []int64
+	done    map[int64]struct{}
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{partitions: make(map[int]*partitionOffsets)}
+}
+
+// track records a message as fetched. Messages have to be tracked in the
+// order they are fetched.
+func (t *offsetTracker) track(m *kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[m.Partition]
+	if ok && len(p.pending) > 0 && m.Offset <= p.pending[len(p.pending)-1] {
+		// The partition was rewound, by a rebalance handing it back to us
+		// or by a reset of the reader. Whatever was in flight will be
+		// fetched again.
+		ok = false
+	}
+
+	if !ok {
+		p = &partitionOffsets{topic: m.Topic, done: make(map[int64]struct{})}
+		t.partitions[m.Partition] = p
+	}
+
+	p.pending = append(p.pending, m.Offset)
+}
+
+// reset forgets every partition, for when the consumer group rebalanced and
+// the partitions may have been handed to other members. Messages still in
+// flight from before are left uncommitted by complete, unless their partition
+// comes back and is fetched again from behind them.
+func (t *offsetTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.partitions = make(map[int]*partitionOffsets)
+}
+
+// complete marks messages as done and returns, per partition, the message
+// whose offset can now be committed.
+func (t *offsetTracker) complete(messages ...*kafka.Message) []kafka.Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	touched := make(map[int]struct{})
+	for _, m := range messages {
+		p, ok := t.partitions[m.Partition]
+		if !ok || len(p.pending) == 0 || m.Offset < p.pending[0] || m.Offset > p.pending[len(p.pending)-1] {
+			// Left over from before the partition was rewound.
+			continue
+		}
+
+		p.done[m.Offset] = struct{}{}
+		touched[m.Partition] = struct{}{}
+	}
+
+	var commits []kafka.Message
+	for partition := range touched {
+		p := t.partitions[partition]
+
+		committed := int64(-1)
+		for len(p.pending) > 0 {
+			offset := p.pending[0]
+			if _, ok := p.done[offset]; !ok {
+				break
+			}
+
+			delete(p.done, offset)
+			p.pending = p.pending[1:]
+			committed = offset
+		}
+
+		if committed >= 0 {
+			commits = append(commits, kafka.Message{Topic: p.topic, Partition: partition, Offset: committed})
+		}
+	}
+
+	return commits
+}
@@ -0,0 +1,125 @@
+package messaging
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// offsetStep optionally resets the tracker, then fetches and completes
+// messages, given as partition and offset pairs. want lists the offsets
+// complete returns per partition.
+type offsetStep struct {
+	reset    bool
+	fetch    [][2]int64
+	complete [][2]int64
+	want     map[int]int64
+}
+
+func TestOffsetTracker(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []offsetStep
+	}{
+		{
+			name: "in order",
+			steps: []offsetStep{
+				{fetch: [][2]int64{{0, 10}, {0, 11}}},
+				{complete: [][2]int64{{0, 10}}, want: map[int]int64{0: 10}},
+				{complete: [][2]int64{{0, 11}}, want: map[int]int64{0: 11}},
+			},
+		},
+		{
+			name: "out of order",
+			steps: []offsetStep{
+				{fetch: [][2]int64{{0, 10}, {0, 11}, {0, 12}}},
+				{complete: [][2]int64{{0, 12}}},
+				{complete: [][2]int64{{0, 11}}},
+				{complete: [][2]int64{{0, 10}}, want: map[int]int64{0: 12}},
+			},
+		},
+		{
+			name: "gap blocks commits",
+			steps: []offsetStep{
+				{fetch: [][2]int64{{0, 10}, {0, 11}, {0, 12}, {0, 13}}},
+				{complete: [][2]int64{{0, 10}, {0, 12}, {0, 13}}, want: map[int]int64{0: 10}},
+				{complete: [][2]int64{{0, 11}}, want: map[int]int64{0: 13}},
+			},
+		},
+		{
+			name: "partitions commit independently",
+			steps: []offsetStep{
+				{fetch: [][2]int64{{0, 10}, {1, 20}, {0, 11}, {1, 21}}},
+				{complete: [][2]int64{{0, 11}, {1, 20}}, want: map[int]int64{1: 20}},
+				{complete: [][2]int64{{0, 10}, {1, 21}}, want: map[int]int64{0: 11, 1: 21}},
+			},
+		},
+		{
+			name: "rewind drops messages in flight",
+			steps: []offsetStep{
+				{fetch: [][2]int64{{0, 10}, {0, 11}, {0, 12}}},
+				{fetch: [][2]int64{{0, 11}}},
+				// 10 is left over from before the rewind.
+				{complete: [][2]int64{{0, 10}}},
+				{complete: [][2]int64{{0, 11}}, want: map[int]int64{0: 11}},
+			},
+		},
+		{
+			name: "reset drops a gap left by a revoked partition",
+			steps: []offsetStep{
+				{fetch: [][2]int64{{0, 10}, {0, 11}}},
+				{complete: [][2]int64{{0, 11}}},
+				// The partition comes back past 10, committed by another member.
+				{reset: true, fetch: [][2]int64{{0, 50}}},
+				{complete: [][2]int64{{0, 10}}},
+				{complete: [][2]int64{{0, 50}}, want: map[int]int64{0: 50}},
+			},
+		},
+		{
+			name: "reset forgets partitions that are not fetched again",
+			steps: []offsetStep{
+				{fetch: [][2]int64{{0, 10}, {1, 20}}},
+				{reset: true, fetch: [][2]int64{{1, 20}}},
+				{complete: [][2]int64{{0, 10}, {1, 20}}, want: map[int]int64{1: 20}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newOffsetTracker()
+
+			for i, step := range tt.steps {
+				if step.reset {
+					tracker.reset()
+				}
+
+				for _, m := range step.fetch {
+					tracker.track(&kafka.Message{Topic: "answers", Partition: int(m[0]), Offset: m[1]})
+				}
+
+				if step.complete == nil {
+					continue
+				}
+
+				var messages []*kafka.Message
+				for _, m := range step.complete {
+					messages = append(messages, &kafka.Message{Topic: "answers", Partition: int(m[0]), Offset: m[1]})
+				}
+
+				got := make(map[int]int64)
+				for _, commit := range tracker.complete(messages...) {
+					if commit.Topic != "answers" {
+						t.Errorf("step %d: commit topic = %q, want answers", i, commit.Topic)
+					}
+					got[commit.Partition] = commit.Offset
+				}
+
+				if !maps.Equal(got, step.want) {
+					t.Errorf("step %d: complete() committed %v, want %v", i, got, step.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy decides how often and how far apart a failing message handler
+// is retried. The delay before attempt n+1 is BaseDelay doubled n-1 times,
+// capped at MaxDelay, of which up to a Jitter fraction is randomly taken off
+// so that consumers failing together do not retry in lockstep.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Jitter      float64
+}
+
+// DefaultRetryPolicy is used by consumers that are not given a policy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+	Jitter:      0.5,
+}
+
+// Do calls execute until it succeeds, fails with a permanent error, runs out
+// of attempts or ctx is done, and returns the last error execute returned.
+func (p RetryPolicy) Do(ctx context.Context, execute func() error) error {
+	attempts := max(p.MaxAttempts, 1)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = execute()
+		if err == nil || IsPermanent(err) || attempt >= attempts {
+			return err
+		}
+
+		timer := time.NewTimer(p.Delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// Delay is how long to wait after the given failed attempt, counting from 1.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	jitter := min(max(p.Jitter, 0), 1)
+	return delay - time.Duration(rand.Float64()*jitter*float64(delay))
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks an error returned by a handler as one that retrying cannot
+// fix, such as a message that fails validation, so that the message goes to
+// the dead letter topic right away.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
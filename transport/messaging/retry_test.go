@@ -0,0 +1,128 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errHandler = errors.New("handler failed")
+
+func TestRetryPolicyDoAttempts(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+	tests := []struct {
+		name      string
+		failures  int
+		err       error
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "succeeds first", failures: 0, err: errHandler, wantCalls: 1},
+		{name: "succeeds on last attempt", failures: 3, err: errHandler, wantCalls: 4},
+		{name: "runs out of attempts", failures: 10, err: errHandler, wantCalls: 4, wantErr: errHandler},
+		{name: "permanent error", failures: 10, err: Permanent(errHandler), wantCalls: 1, wantErr: errHandler},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := policy.Do(context.Background(), func() error {
+				calls++
+				if calls <= tt.failures {
+					return tt.err
+				}
+				return nil
+			})
+
+			if calls != tt.wantCalls {
+				t.Errorf("Do() called execute %d times, want %d", calls, tt.wantCalls)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Do() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDoWithoutAttempts(t *testing.T) {
+	calls := 0
+	_ = RetryPolicy{}.Do(context.Background(), func() error {
+		calls++
+		return errHandler
+	})
+
+	if calls != 1 {
+		t.Errorf("Do() with no MaxAttempts called execute %d times, want 1", calls)
+	}
+}
+
+func TestRetryPolicyDoCancelled(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	calls := 0
+	done := make(chan error, 1)
+	go func() {
+		done <- policy.Do(ctx, func() error {
+			calls++
+			return errHandler
+		})
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, errHandler) {
+			t.Errorf("Do() error = %v, want %v", err, errHandler)
+		}
+		if calls != 1 {
+			t.Errorf("Do() called execute %d times, want 1", calls)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Do() kept waiting after ctx was cancelled")
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	tests := map[int]time.Duration{
+		1:  100 * time.Millisecond,
+		2:  200 * time.Millisecond,
+		3:  400 * time.Millisecond,
+		4:  800 * time.Millisecond,
+		5:  time.Second,
+		50: time.Second,
+	}
+
+	for attempt, want := range tests {
+		if got := policy.Delay(attempt); got != want {
+			t.Errorf("Delay(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
+
+func TestRetryPolicyDelayJitter(t *testing.T) {
+	tests := []struct {
+		jitter float64
+		min    time.Duration
+	}{
+		{jitter: 0.5, min: 500 * time.Millisecond},
+		{jitter: 1, min: 0},
+		{jitter: 2, min: 0},
+		{jitter: -1, min: time.Second},
+	}
+
+	for _, tt := range tests {
+		policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Second, Jitter: tt.jitter}
+
+		for range 1000 {
+			if got := policy.Delay(3); got < tt.min || got > time.Second {
+				t.Fatalf("Delay(3) with jitter %v = %s, want within [%s, 1s]", tt.jitter, got, tt.min)
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// EventTypeHeader names the kind of event a message carries, on topics that
+// carry more than one kind.
+const EventTypeHeader = "event-type"
+
+// EventIDHeader identifies the event a message carries, for producers that
+// may publish the same event more than once.
+const EventIDHeader = "event-id"
+
+// defaultHandlerName labels the metrics of the handler a consumer is created
+// with.
+const defaultHandlerName = "default"
+
+// ErrNoHandler fails messages of an event type the consumer has no handler
+// for, sending them to the dead letter topic.
+var ErrNoHandler = errors.New("no handler for event type")
+
+// handlerRoute is where the messages of one event type go.
+type handlerRoute struct {
+	name            string
+	handler         HandlerFunc
+	deadletterTopic string
+	deadletter      *kafka.Writer
+	metrics         *handlerMetrics
+}
+
+// WithEventHandler hands messages whose event-type header is eventType to
+// handler instead of to the consumer's own handler, dead lettering those it
+// fails on to deadletterTopic, or to the consumer's dead letter topic if that
+// is empty. With batching, these messages are handled one at a time alongside
+// the batches of the consumer's own handler.
+func WithEventHandler(eventType string, handler HandlerFunc, deadletterTopic string) ConsumerOption {
+	return func(kh *KafkaConsumer) {
+		kh.routes[eventType] = &handlerRoute{
+			name:            eventType,
+			handler:         handler,
+			deadletterTopic: deadletterTopic,
+		}
+	}
+}
+
+// routeFor picks the handler of a message by its event-type header.
+func (kh *KafkaConsumer) routeFor(m *kafka.Message) *handlerRoute {
+	if len(kh.routes) == 0 {
+		return kh.fallback
+	}
+
+	for _, header := range m.Headers {
+		if header.Key == EventTypeHeader {
+			if route, ok := kh.routes[string(header.Value)]; ok {
+				return route
+			}
+			break
+		}
+	}
+
+	return kh.fallback
+}
+
+// unroutable is the consumer's own handler when it is created without one,
+// for topics whose every event type has a handler of its own.
+func unroutable(ctx context.Context, m *kafka.Message) error {
+	for _, header := range m.Headers {
+		if header.Key == EventTypeHeader {
+			return Permanent(fmt.Errorf("%w %q", ErrNoHandler, header.Value))
+		}
+	}
+
+	return Permanent(fmt.Errorf("%w, message has no %s header", ErrNoHandler, EventTypeHeader))
+}
+
+// Router consumes several topics as members of one consumer group, each
+// topic through a KafkaConsumer of its own with its own handlers, dead letter
+// topics and metrics.
+type Router struct {
+	brokers   []string
+	groupID   string
+	logger    *zerolog.Logger
+	tp        *sdktrace.TracerProvider
+	consumers []*KafkaConsumer
+	done      chan struct{}
+}
+
+func NewRouter(brokers []string, consumerGroupID string, logger *zerolog.Logger, tp *sdktrace.TracerProvider) *Router {
+	return &Router{
+		brokers: brokers,
+		groupID: consumerGroupID,
+		logger:  logger,
+		tp:      tp,
+		done:    make(chan struct{}),
+	}
+}
+
+// Handle consumes topic with handler, which may be nil if WithEventHandler
+// gives every event type of the topic a handler.
+func (r *Router) Handle(topic string, handler HandlerFunc, deadletterTopic string, opts ...ConsumerOption) *KafkaConsumer {
+	consumer := NewKafkaConsumer(r.brokers, topic, r.groupID, handler, deadletterTopic, r.logger, r.tp, opts...)
+	r.consumers = append(r.consumers, consumer)
+
+	return consumer
+}
+
+// Start starts every topic's consumer with workerCount workers each. Done is
+// closed once all of them have stopped after ctx is done.
+func (r *Router) Start(ctx context.Context, workerCount int, mp metric.MeterProvider) {
+	for _, consumer := range r.consumers {
+		consumer.Start(ctx, workerCount, mp)
+	}
+
+	go func() {
+		for _, consumer := range r.consumers {
+			<-consumer.Done()
+		}
+		close(r.done)
+	}()
+}
+
+// Done is closed once every consumer of the router has stopped.
+func (r *Router) Done() <-chan struct{} {
+	return r.done
+}